@@ -0,0 +1,65 @@
+package api
+
+import (
+	"billing/api/response"
+	"billing/internal/apperror"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NewHTTPErrorHandler turns every error returned by a handler into the
+// response.Response envelope, mapping domain errors to their HTTP status.
+func NewHTTPErrorHandler(logger *slog.Logger) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		handleError(logger, err, c)
+	}
+}
+
+func handleError(logger *slog.Logger, err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	ctx := c.Request().Context()
+
+	var sendErr error
+	if appErr, ok := apperror.From(err); ok {
+		detail := response.ErrorDetail{Code: appErr.Code}
+		if len(appErr.Fields) > 0 {
+			detail.Fields = appErr.Fields
+		}
+		sendErr = response.ErrorWithDetail(c, statusOf(appErr.Kind), appErr.Message, detail)
+	} else if he := (*echo.HTTPError)(nil); errors.As(err, &he) {
+		sendErr = response.Error(c, he.Code, fmt.Sprint(he.Message))
+	} else {
+		logger.ErrorContext(ctx, "unhandled error", "method", c.Request().Method, "route", c.Path(), "error", err)
+		sendErr = response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+	}
+
+	if sendErr != nil {
+		logger.ErrorContext(ctx, "send error response failed", "error", sendErr)
+	}
+}
+
+func statusOf(kind apperror.Kind) int {
+	switch kind {
+	case apperror.KindNotFound:
+		return http.StatusNotFound
+	case apperror.KindConflict:
+		return http.StatusConflict
+	case apperror.KindValidationFailed:
+		return http.StatusBadRequest
+	case apperror.KindBusinessRuleViolated:
+		return http.StatusUnprocessableEntity
+	case apperror.KindUnauthenticated:
+		return http.StatusUnauthorized
+	case apperror.KindForbidden:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -2,19 +2,21 @@ package handler
 
 import (
 	"billing/api/response"
+	"billing/internal/export"
+	"billing/internal/logging"
 	"billing/internal/model"
 	"billing/internal/usecase"
-	"errors"
-	"fmt"
-	"net/http"
+	"billing/internal/validation"
+	"bytes"
+	"log/slog"
 	"strings"
 
 	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
 )
 
 type BillingHandler struct {
 	LoanUsecase *usecase.LoanUsecase
+	Logger      *slog.Logger
 }
 
 /*
@@ -23,20 +25,19 @@ REQUIRED RESPONSE :
 - All field in Bills
 */
 func (h *BillingHandler) CreateBills(c echo.Context) error {
-	req := model.Loan{}
+	req := model.ProductLoan{}
 
-	err := c.Bind(&req)
-	if err != nil {
-		return response.Error(c, http.StatusBadRequest, err.Error())
+	if err := c.Bind(&req); err != nil {
+		return err
 	}
 
-	if req.CustomerID == "0" || req.CustomerID == "" {
-		return response.Error(c, http.StatusBadRequest, "customer_id is required")
+	if err := c.Validate(&req); err != nil {
+		return err
 	}
 
-	resp, err := h.LoanUsecase.CreateBills(req)
+	resp, err := h.LoanUsecase.CreateBills(c.Request().Context(), req)
 	if err != nil {
-		return response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return err
 	}
 
 	return response.Created(c, resp)
@@ -50,16 +51,32 @@ REQUIRED RESPONSE :
 func (h *BillingHandler) GetBills(c echo.Context) error {
 	loanID := strings.TrimSpace(c.Param("loan_id"))
 
-	if loanID == "" || loanID == "0" {
-		return response.Error(c, http.StatusBadRequest, "loan_id is required")
+	if err := validation.LoanID(loanID).Err(); err != nil {
+		return err
+	}
+
+	format := exportFormat(c)
+	if err := checkExportFormat(format, export.FormatCSV, export.FormatXLSX); err != nil {
+		return err
 	}
 
-	resp, err := h.LoanUsecase.GetBills(loanID)
+	resp, err := h.LoanUsecase.GetBills(c.Request().Context(), loanID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return response.Error(c, http.StatusBadRequest, fmt.Sprintf("loan_id %s not found", loanID))
+		return err
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case export.FormatCSV:
+		if err := export.WriteScheduleCSV(&buf, *resp); err != nil {
+			return err
+		}
+		return sendFile(c, export.MIMECSV, "loan-"+loanID+"-schedule.csv", buf.Bytes())
+	case export.FormatXLSX:
+		if err := export.WriteScheduleXLSX(&buf, *resp); err != nil {
+			return err
 		}
-		return response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return sendFile(c, export.MIMEXLSX, "loan-"+loanID+"-schedule.xlsx", buf.Bytes())
 	}
 
 	return response.Success(c, resp)
@@ -74,16 +91,13 @@ REQUIRED RESPONSE :
 func (h *BillingHandler) GetBillStatus(c echo.Context) error {
 	loanID := strings.TrimSpace(c.Param("loan_id"))
 
-	if loanID == "" || loanID == "0" {
-		return response.Error(c, http.StatusBadRequest, "loan_id is required")
+	if err := validation.LoanID(loanID).Err(); err != nil {
+		return err
 	}
 
-	resp, err := h.LoanUsecase.GetBillStatus(loanID)
+	resp, err := h.LoanUsecase.GetBillStatus(c.Request().Context(), loanID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return response.Error(c, http.StatusBadRequest, fmt.Sprintf("loan_id %s not found", loanID))
-		}
-		return response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return err
 	}
 
 	return response.Success(c, resp)
@@ -98,27 +112,26 @@ REQUIRED RESPONSE :
 func (h *BillingHandler) MakePayment(c echo.Context) error {
 	loanID := strings.TrimSpace(c.Param("loan_id"))
 
+	if err := validation.LoanID(loanID).Err(); err != nil {
+		return err
+	}
+
 	req := model.MakePaymentRequest{}
 
-	err := c.Bind(&req)
-	if err != nil {
-		return response.Error(c, http.StatusBadRequest, err.Error())
+	if err := c.Bind(&req); err != nil {
+		return err
 	}
-	fmt.Println("NAH", req)
 
-	if req.PaymentAmount < 0 {
-		return response.Error(c, http.StatusBadRequest, "payment amount less than 0")
+	if err := c.Validate(&req); err != nil {
+		return err
 	}
 
 	req.LoanID = loanID
-	fmt.Println("INU", req)
+	logging.OrDefault(h.Logger).DebugContext(c.Request().Context(), "payment requested", "loan_id", req.LoanID, "amount", req.PaymentAmount, "payment_date", req.PaymentDate)
 
-	resp, err := h.LoanUsecase.MakePayment(req)
+	resp, err := h.LoanUsecase.MakePayment(c.Request().Context(), req)
 	if err != nil {
-		if errors.Is(err, usecase.ErrInsufficientAmount) || errors.Is(err, usecase.ErrNoPendingBill){
-			return response.Error(c, http.StatusBadRequest, err.Error())
-		}
-		return response.Error(c, http.StatusInternalServerError, "Internal Server Error")
+		return err
 	}
 
 	return response.Success(c, resp)
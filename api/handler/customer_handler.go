@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"billing/api/response"
+	"billing/internal/model"
+	"billing/internal/usecase"
+	"billing/internal/validation"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type CustomerHandler struct {
+	LoanUsecase *usecase.LoanUsecase
+}
+
+// CreateCustomer registers a borrower.
+func (h *CustomerHandler) CreateCustomer(c echo.Context) error {
+	req := model.CreateCustomerRequest{}
+
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	resp, err := h.LoanUsecase.CreateCustomer(c.Request().Context(), req)
+	if err != nil {
+		return err
+	}
+
+	return response.Created(c, resp)
+}
+
+// GetCustomerLoans lists a customer's loans with their outstanding and
+// delinquency.
+func (h *CustomerHandler) GetCustomerLoans(c echo.Context) error {
+	customerID := strings.TrimSpace(c.Param("customer_id"))
+
+	if err := validation.CustomerID(customerID).Err(); err != nil {
+		return err
+	}
+
+	resp, err := h.LoanUsecase.ListCustomerLoans(c.Request().Context(), customerID)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, resp)
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"billing/internal/export"
+	"billing/internal/validation"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// exportFormat picks the file format a client asked for, from a format query
+// parameter or else the Accept header. It returns "" for the usual JSON.
+func exportFormat(c echo.Context) string {
+	if format := c.QueryParam("format"); format != "" {
+		return format
+	}
+	for _, accepted := range strings.Split(c.Request().Header.Get(echo.HeaderAccept), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accepted))
+		switch mediaType {
+		case export.MIMECSV:
+			return export.FormatCSV
+		case export.MIMEXLSX:
+			return export.FormatXLSX
+		case echo.MIMEApplicationJSON, "*/*":
+			return ""
+		}
+	}
+	return ""
+}
+
+// sendFile sends body as a download named filename.
+func sendFile(c echo.Context, contentType, filename string, body []byte) error {
+	c.Response().Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	return c.Blob(http.StatusOK, contentType, body)
+}
+
+// checkExportFormat rejects formats other than JSON and the given ones.
+func checkExportFormat(format string, allowed ...string) error {
+	if format == "" || slices.Contains(allowed, format) {
+		return nil
+	}
+	var errs validation.Errors
+	errs.Add("format", validation.CodeNotAllowed, "format must be one of %s", strings.Join(allowed, ", "))
+	return errs
+}
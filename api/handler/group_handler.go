@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"billing/api/response"
+	"billing/internal/model"
+	"billing/internal/usecase"
+	"billing/internal/validation"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type GroupHandler struct {
+	LoanUsecase *usecase.LoanUsecase
+}
+
+// CreateGroup creates a group from existing loans.
+func (h *GroupHandler) CreateGroup(c echo.Context) error {
+	req := model.CreateGroupRequest{}
+
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	resp, err := h.LoanUsecase.CreateGroup(c.Request().Context(), req)
+	if err != nil {
+		return err
+	}
+
+	return response.Created(c, resp)
+}
+
+// GetGroup shows the group's loans with combined outstanding and delinquency.
+func (h *GroupHandler) GetGroup(c echo.Context) error {
+	groupID := strings.TrimSpace(c.Param("group_id"))
+
+	if err := validation.GroupID(groupID).Err(); err != nil {
+		return err
+	}
+
+	resp, err := h.LoanUsecase.GetGroup(c.Request().Context(), groupID)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, resp)
+}
+
+// AddGroupLoans adds existing loans to a group.
+func (h *GroupHandler) AddGroupLoans(c echo.Context) error {
+	groupID := strings.TrimSpace(c.Param("group_id"))
+
+	if err := validation.GroupID(groupID).Err(); err != nil {
+		return err
+	}
+
+	req := model.AddGroupLoansRequest{}
+
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	resp, err := h.LoanUsecase.AddGroupLoans(c.Request().Context(), groupID, req.LoanIDs)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, resp)
+}
+
+// MakeGroupPayment spreads a lump sum over the bills due on the group's loans.
+func (h *GroupHandler) MakeGroupPayment(c echo.Context) error {
+	groupID := strings.TrimSpace(c.Param("group_id"))
+
+	if err := validation.GroupID(groupID).Err(); err != nil {
+		return err
+	}
+
+	req := model.GroupPaymentRequest{}
+
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	resp, err := h.LoanUsecase.MakeGroupPayment(c.Request().Context(), groupID, req)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, resp)
+}
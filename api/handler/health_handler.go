@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"billing/api/response"
+	"billing/internal/buildinfo"
+	"billing/internal/logging"
+	"billing/internal/repository"
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const readinessTimeout = 2 * time.Second
+
+type HealthHandler struct {
+	Health repository.HealthChecker
+	Logger *slog.Logger
+}
+
+// Healthz reports that the process is up; it never touches dependencies.
+func (h *HealthHandler) Healthz(c echo.Context) error {
+	return response.Success(c, map[string]string{"status": "ok"})
+}
+
+// Readyz reports whether the service can take traffic: the database answers
+// and its schema is at the version this binary expects.
+func (h *HealthHandler) Readyz(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), readinessTimeout)
+	defer cancel()
+
+	if err := h.Health.Ready(ctx); err != nil {
+		// The cause names database and schema details, so it only goes to
+		// the log; probes just need the status.
+		logging.OrDefault(h.Logger).WarnContext(ctx, "not ready", "error", err)
+		return response.ErrorWithDetail(c, http.StatusServiceUnavailable, "not ready", response.ErrorDetail{Code: "NOT_READY"})
+	}
+
+	return response.Success(c, map[string]string{"status": "ready"})
+}
+
+// Version returns the build metadata of the running binary.
+func (h *HealthHandler) Version(c echo.Context) error {
+	return response.Success(c, buildinfo.Get())
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"billing/api/response"
+	"billing/internal/csvfile"
+	"billing/internal/model"
+	"billing/internal/usecase"
+	"billing/internal/validation"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CreateLoansBatch creates many loans at once and reports each one. The body
+// is a JSON array of POST /bills requests, or a CSV file (Content-Type
+// text/csv, or the "file" part of a multipart form) with the same fields as
+// columns.
+func (h *LoanHandler) CreateLoansBatch(c echo.Context) error {
+	var rows []usecase.LoanBatchRow
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	if strings.HasPrefix(contentType, "text/csv") || strings.HasPrefix(contentType, echo.MIMEMultipartForm) {
+		file, _, err := uploadedFile(c)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if rows, err = readLoansCSV(file); err != nil {
+			return err
+		}
+	} else {
+		var loans []model.ProductLoan
+		if err := c.Bind(&loans); err != nil {
+			return err
+		}
+		for i, loan := range loans {
+			rows = append(rows, usecase.LoanBatchRow{Row: i + 1, Loan: loan})
+		}
+	}
+
+	resp, err := h.LoanUsecase.CreateBillsBatch(c.Request().Context(), rows)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, resp)
+}
+
+var loanCSVRequired = []string{"customer_id", "amount", "period"}
+
+// readLoansCSV reads loan requests from a file whose header names at least
+// loanCSVRequired; product_code, name and interest_rate are optional.
+func readLoansCSV(r io.Reader) ([]usecase.LoanBatchRow, error) {
+	var rows []usecase.LoanBatchRow
+	err := csvfile.Read(r, loanCSVRequired, func(rec csvfile.Record) {
+		row := usecase.LoanBatchRow{Row: rec.Line}
+		row.Loan.CustomerID = rec.Field("customer_id")
+		row.Loan.ProductCode = rec.Field("product_code")
+		row.Loan.Name = rec.Field("name")
+		if v := rec.Field("period"); v != "" {
+			period, err := strconv.Atoi(v)
+			if err != nil {
+				row.Err.Add("period", validation.CodeInvalidFormat, "period %q is not a whole number", v)
+			}
+			row.Loan.Period = period
+		}
+		row.Loan.Amount = csvNumber(&row.Err, "amount", rec.Field("amount"))
+		row.Loan.InterestRate = csvNumber(&row.Err, "interest_rate", rec.Field("interest_rate"))
+		rows = append(rows, row)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// csvNumber parses an optional number; an empty cell reads as 0 and is left
+// to the request rules.
+func csvNumber(errs *validation.Errors, field, v string) float64 {
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		errs.Add(field, validation.CodeInvalidFormat, "%s %q is not a number", field, v)
+	}
+	return n
+}
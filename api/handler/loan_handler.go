@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"billing/api/response"
+	"billing/internal/export"
+	"billing/internal/usecase"
+	"billing/internal/validation"
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type LoanHandler struct {
+	LoanUsecase *usecase.LoanUsecase
+}
+
+// SearchLoans lists loans matching the query filters, one page at a time.
+func (h *LoanHandler) SearchLoans(c echo.Context) error {
+	search, errs := validation.LoanSearch(c.QueryParams())
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	resp, err := h.LoanUsecase.SearchLoans(c.Request().Context(), search)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, resp)
+}
+
+// Statement renders the loan's statement as of a date as a PDF.
+func (h *LoanHandler) Statement(c echo.Context) error {
+	loanID := strings.TrimSpace(c.Param("loan_id"))
+
+	errs := validation.LoanID(loanID)
+	asOf, dateErrs := validation.StatementDate(c.QueryParams())
+	if err := append(errs, dateErrs...).Err(); err != nil {
+		return err
+	}
+
+	st, err := h.LoanUsecase.Statement(c.Request().Context(), loanID, asOf)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteStatementPDF(&buf, *st); err != nil {
+		return err
+	}
+	filename := fmt.Sprintf("loan-%s-statement-%s.pdf", loanID, asOf.Format(time.DateOnly))
+	return sendFile(c, export.MIMEPDF, filename, buf.Bytes())
+}
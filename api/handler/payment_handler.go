@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"billing/api/response"
+	"billing/internal/payfile"
+	"billing/internal/usecase"
+
+	"github.com/labstack/echo/v4"
+)
+
+type PaymentHandler struct {
+	ImportUsecase *usecase.PaymentImportUsecase
+}
+
+// ImportPayments posts every payment in a bank file and reports each row. The
+// file is either the "file" part of a multipart form or the raw request body;
+// format is taken from the format query parameter, else from the file name.
+func (h *PaymentHandler) ImportPayments(c echo.Context) error {
+	file, source, err := uploadedFile(c)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	format := c.QueryParam("format")
+	if format == "" {
+		format = payfile.FormatFor(source)
+	}
+	rows, err := payfile.Parse(file, format)
+	if err != nil {
+		return err
+	}
+	if source == "" {
+		source = "upload"
+	}
+
+	resp, err := h.ImportUsecase.Import(c.Request().Context(), source, rows)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, resp)
+}
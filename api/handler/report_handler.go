@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"billing/api/response"
+	"billing/internal/export"
+	"billing/internal/model"
+	"billing/internal/usecase"
+	"billing/internal/validation"
+	"mime"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ReportHandler struct {
+	ReportUsecase *usecase.ReportUsecase
+}
+
+// Portfolio reports the loan book for the from/to period.
+func (h *ReportHandler) Portfolio(c echo.Context) error {
+	period, errs := validation.ReportPeriod(c.QueryParams())
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	resp, err := h.ReportUsecase.Portfolio(c.Request().Context(), period)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, resp)
+}
+
+// CollectionsDue lists the bills field officers should collect on a date.
+func (h *ReportHandler) CollectionsDue(c echo.Context) error {
+	search, errs := validation.CollectionsDue(c.QueryParams())
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	resp, err := h.ReportUsecase.CollectionsDue(c.Request().Context(), search)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, resp)
+}
+
+// exportFlushEvery is how many rows ExportLoans buffers before sending them.
+const exportFlushEvery = 500
+
+// ExportLoans streams every loan matching the GET /loans filters as CSV.
+// Rows are written as they are read, so once the first ones are sent a
+// failure can only cut the file short; the error is still logged.
+func (h *ReportHandler) ExportLoans(c echo.Context) error {
+	search, errs := validation.LoanSearch(c.QueryParams())
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, export.MIMECSV)
+	w.Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": "loans.csv"}))
+	w.WriteHeader(http.StatusOK)
+
+	out, err := export.NewLoanCSV(w)
+	if err != nil {
+		return err
+	}
+	rows := 0
+	err = h.ReportUsecase.ExportLoans(c.Request().Context(), search, func(loan model.ProductLoan) error {
+		if err := out.Write(loan); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushEvery == 0 {
+			if err := out.Flush(); err != nil {
+				return err
+			}
+			w.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return out.Flush()
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"billing/internal/validation"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxUploadSize bounds an uploaded file.
+const maxUploadSize = 10 << 20
+
+// uploadedFile returns the file a client sent, either as the "file" part of a
+// multipart form or as the raw request body, with its name: the part's file
+// name, else the filename query parameter. The caller closes the file.
+func uploadedFile(c echo.Context) (io.ReadCloser, string, error) {
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, maxUploadSize)
+
+	if !strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		return req.Body, strings.TrimSpace(c.QueryParam("filename")), nil
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var errs validation.Errors
+		errs.Add("file", validation.CodeRequired, "file is required: %v", err)
+		return nil, "", errs
+	}
+	var file multipart.File
+	if file, err = fh.Open(); err != nil {
+		return nil, "", err
+	}
+	return file, fh.Filename, nil
+}
@@ -2,35 +2,147 @@ package api
 
 import (
 	"billing/api/handler"
-	"billing/internal/model"
+	"billing/internal/auth"
+	"billing/internal/cli"
+	"billing/internal/config"
+	"billing/internal/logging"
+	"billing/internal/metrics"
+	"billing/internal/repository"
 	"billing/internal/usecase"
+	"billing/internal/validation"
 	"billing/pkg/db"
+	"context"
 	"log"
+	"log/slog"
+	"os"
 
 	"github.com/labstack/echo/v4"
 )
 
 // INIT YOUR DEPENDENCY HERE
 func Init() *echo.Echo {
-	e := echo.New()
+	// main only builds and starts the server, so the binary's subcommands
+	// are dispatched here, before anything is opened.
+	if cli.IsCommand(os.Args[1:]) {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
+	cfg := loadConfig()
+	slog.SetDefault(newLogger(cfg))
 
-	// IF YOU WANT TO USE DATABASE
-	// YOU NEED TO ASSIGN TO VARIABLE AND PASS TO ROUTE
-	// YOU NEED TO FILL STRUCT IN THIS FUNCTION TO CREATE TABLE IN DB
-	// EXAMPLE : _, err := db.InitAndMigrate(&model.Loan{}, &model.InvestLoan{})
-	db, err := db.InitAndMigrate(&model.Loan{}, &model.Billing{})
+	// SCHEMA CHANGES GO IN pkg/db/migrations, InitAndMigrate APPLIES PENDING ONES
+	db, err := db.InitAndMigrate(cfg.DB)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	loanUsecase := usecase.LoanUsecase {
-		DB: db,
+	e := New(cfg, repository.NewGormRepositories(db))
+	server := Server{Echo: e, Config: cfg, DB: db}
+	server.Attach()
+	return e
+}
+
+// New wires the handlers and usecases on top of the given storage backend.
+func New(cfg config.Config, repos repository.Repositories) *echo.Echo {
+	logger := newLogger(cfg)
+
+	e := echo.New()
+	e.Validator = &validation.Validator{
+		MaxPaymentLead: cfg.Loan.MaxPaymentLead(),
 	}
+	e.HTTPErrorHandler = NewHTTPErrorHandler(logger)
 
-	handler := handler.BillingHandler {
+	productUsecase := usecase.ProductUsecase{
+		Products: repos.Products,
+		Logger:   logger,
+	}
+	if err := productUsecase.SeedDefaults(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+
+	loanUsecase := usecase.LoanUsecase{
+		Loans:                     repos.Loans,
+		Billings:                  repos.Billings,
+		Products:                  repos.Products,
+		Customers:                 repos.Customers,
+		Groups:                    repos.Groups,
+		DefaultProductCode:        cfg.Loan.DefaultProductCode,
+		DelinquencyThreshold:      cfg.Loan.DelinquencyThreshold,
+		RequireRegisteredCustomer: cfg.Loan.RequireRegisteredCustomer,
+		Eligibility: usecase.EligibilityRules{
+			MaxActiveLoans:      cfg.Loan.MaxActiveLoans,
+			MaxExposure:         cfg.Loan.MaxExposure,
+			BlockWhenDelinquent: cfg.Loan.BlockWhenDelinquent,
+		},
+		Logger: logger,
+	}
+	m := metrics.New(loanUsecase.DelinquencySummary)
+	loanUsecase.Metrics = m
+
+	billing := handler.BillingHandler{
+		LoanUsecase: &loanUsecase,
+		Logger:      logger,
+	}
+
+	customer := handler.CustomerHandler{
 		LoanUsecase: &loanUsecase,
 	}
 
-	RegisterRoutes(e, handler)
+	loans := handler.LoanHandler{
+		LoanUsecase: &loanUsecase,
+	}
+
+	reports := handler.ReportHandler{
+		ReportUsecase: &usecase.ReportUsecase{
+			Reports:              repos.Reports,
+			Loans:                repos.Loans,
+			DelinquencyThreshold: cfg.Loan.DelinquencyThreshold,
+			Logger:               logger,
+		},
+	}
+
+	groups := handler.GroupHandler{
+		LoanUsecase: &loanUsecase,
+	}
+
+	payments := handler.PaymentHandler{
+		ImportUsecase: &usecase.PaymentImportUsecase{
+			Loans:          &loanUsecase,
+			Imports:        repos.Imports,
+			MaxPaymentLead: cfg.Loan.MaxPaymentLead(),
+			Logger:         logger,
+		},
+	}
+
+	health := handler.HealthHandler{
+		Health: repos.Health,
+		Logger: logger,
+	}
+
+	authn, err := auth.NewAuthenticator(cfg.Auth)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !authn.Enabled() {
+		logger.Warn("authentication disabled by auth.disabled: every request is accepted without credentials")
+	}
+
+	RegisterRoutes(e, billing, customer, loans, groups, reports, payments, health, m, logger, authn)
 	return e
 }
+
+// newLogger writes JSON lines to stdout; Load has already validated the level.
+func newLogger(cfg config.Config) *slog.Logger {
+	level, _ := cfg.Log.SlogLevel()
+	return logging.New(os.Stdout, level)
+}
+
+// loadConfig stops the process with every invalid setting listed, so a bad
+// deploy fails at startup instead of on the first request.
+func loadConfig() config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return cfg
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"billing/internal/auth"
+	"billing/internal/logging"
+	"billing/internal/metrics"
+	"billing/internal/tracing"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDMiddleware echoes the caller's X-Request-ID, or a new one, and puts
+// it on the request context so every log line for the request carries it.
+func requestIDMiddleware() echo.MiddlewareFunc {
+	return middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		Generator: func() string {
+			return uuid.New().String()
+		},
+		RequestIDHandler: func(c echo.Context, id string) {
+			req := c.Request()
+			c.SetRequest(req.WithContext(logging.WithRequestID(req.Context(), id)))
+		},
+	})
+}
+
+// tracingMiddleware opens a server span per non-probe request, continuing the
+// caller's trace when it sent a traceparent header.
+func tracingMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if isProbe(c.Path()) {
+				return next(c)
+			}
+
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			ctx, span := tracing.Tracer().Start(ctx, req.Method+" "+c.Path(),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(req.Method),
+					semconv.HTTPRoute(c.Path()),
+				),
+			)
+			defer span.End()
+			if loanID := c.Param("loan_id"); loanID != "" {
+				span.SetAttributes(tracing.LoanID(loanID))
+			}
+			c.SetRequest(req.WithContext(ctx))
+
+			if err := next(c); err != nil {
+				span.RecordError(err)
+				c.Error(err)
+			}
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			return nil
+		}
+	}
+}
+
+// requestLogMiddleware writes one access log line per non-probe request.
+func requestLogMiddleware(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if isProbe(c.Path()) {
+				return next(c)
+			}
+
+			start := time.Now()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+			req := c.Request()
+			logger.InfoContext(req.Context(), "request",
+				"method", req.Method,
+				"route", c.Path(),
+				"status", c.Response().Status,
+				"duration_ms", time.Since(start).Milliseconds(),
+			)
+			return nil
+		}
+	}
+}
+
+// metricsMiddleware records the latency of every non-probe request. Errors are
+// rendered first so the observed status is the one the client received.
+func metricsMiddleware(m *metrics.Metrics) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if isProbe(c.Path()) {
+				return next(c)
+			}
+
+			start := time.Now()
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+			m.ObserveRequest(c.Request().Method, c.Path(), c.Response().Status, time.Since(start))
+			return nil
+		}
+	}
+}
+
+// authMiddleware identifies the caller of every request but the public probes
+// and puts it on the request context. With authentication disabled every
+// request passes.
+func authMiddleware(authn *auth.Authenticator, logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !authn.Enabled() || isPublic(c.Path()) {
+				return next(c)
+			}
+
+			req := c.Request()
+			principal, err := authn.Authenticate(req)
+			if err != nil {
+				logger.WarnContext(req.Context(), "authentication failed", "route", c.Path(), "error", err)
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return err
+			}
+
+			c.SetRequest(req.WithContext(auth.WithPrincipal(req.Context(), principal)))
+			return next(c)
+		}
+	}
+}
+
+// requirePermission rejects callers whose roles do not grant perm.
+func requirePermission(authn *auth.Authenticator, perm auth.Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !authn.Enabled() {
+				return next(c)
+			}
+
+			principal, ok := auth.FromContext(c.Request().Context())
+			if !ok || !principal.Can(perm) {
+				return auth.ErrForbidden
+			}
+			return next(c)
+		}
+	}
+}
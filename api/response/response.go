@@ -41,3 +41,19 @@ func Error(c echo.Context, status int, message string) error {
 		Message: message,
 	})
 }
+
+// ErrorDetail is the data of an error response: a stable code and, when the
+// request failed validation, every rejected field.
+type ErrorDetail struct {
+	Code   string `json:"code"`
+	Fields any    `json:"fields,omitempty"`
+}
+
+// ErrorWithDetail sends an error response carrying a machine-readable detail.
+func ErrorWithDetail(c echo.Context, status int, message string, detail ErrorDetail) error {
+	return c.JSON(status, Response{
+		Status:  status,
+		Message: message,
+		Data:    detail,
+	})
+}
@@ -2,14 +2,61 @@ package api
 
 import (
 	"billing/api/handler"
+	"billing/internal/auth"
+	"billing/internal/metrics"
+	"log/slog"
 
 	"github.com/labstack/echo/v4"
 )
 
+// probePaths are polled by orchestrators and scrapers rather than clients, so
+// request logging, tracing and metrics leave them out. The value tells whether
+// the probe is public; /metrics exposes portfolio figures and needs
+// credentials like any other route.
+var probePaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/version": true,
+	"/metrics": false,
+}
+
+func isProbe(path string) bool {
+	_, ok := probePaths[path]
+	return ok
+}
+
+func isPublic(path string) bool {
+	return probePaths[path]
+}
+
 // DON'T CHANGE ANY PATH & METHOD
-func RegisterRoutes(e *echo.Echo, handler handler.BillingHandler) {
-	e.GET("/bills/:loan_id", handler.GetBills)
-	e.GET("/bills/:loan_id/status", handler.GetBillStatus)
-	e.POST("/bills", handler.CreateBills)
-	e.POST("/bills/:loan_id/payments", handler.MakePayment)
+func RegisterRoutes(e *echo.Echo, handler handler.BillingHandler, customer handler.CustomerHandler, loans handler.LoanHandler, groups handler.GroupHandler, reports handler.ReportHandler, payments handler.PaymentHandler, health handler.HealthHandler, m *metrics.Metrics, logger *slog.Logger, authn *auth.Authenticator) {
+	e.Use(requestIDMiddleware(), tracingMiddleware(), requestLogMiddleware(logger), metricsMiddleware(m), authMiddleware(authn, logger))
+
+	e.GET("/healthz", health.Healthz)
+	e.GET("/readyz", health.Readyz)
+	e.GET("/version", health.Version)
+	e.GET("/metrics", echo.WrapHandler(m.Handler()), requirePermission(authn, auth.PermReadMetrics))
+
+	read := requirePermission(authn, auth.PermReadLoans)
+	e.GET("/bills/:loan_id", handler.GetBills, read)
+	e.GET("/bills/:loan_id/status", handler.GetBillStatus, read)
+	e.POST("/bills", handler.CreateBills, requirePermission(authn, auth.PermCreateLoans))
+	e.POST("/bills/:loan_id/payments", handler.MakePayment, requirePermission(authn, auth.PermCreatePayments))
+	e.POST("/payments/import", payments.ImportPayments, requirePermission(authn, auth.PermImportPayments))
+
+	e.POST("/customers", customer.CreateCustomer, requirePermission(authn, auth.PermCreateCustomers))
+	e.GET("/customers/:customer_id/loans", customer.GetCustomerLoans, read)
+	e.GET("/loans", loans.SearchLoans, read)
+	e.POST("/loans/batch", loans.CreateLoansBatch, requirePermission(authn, auth.PermCreateLoans))
+	e.GET("/loans/:loan_id/statement.pdf", loans.Statement, read)
+
+	e.POST("/groups", groups.CreateGroup, requirePermission(authn, auth.PermCreateLoans))
+	e.GET("/groups/:group_id", groups.GetGroup, read)
+	e.POST("/groups/:group_id/loans", groups.AddGroupLoans, requirePermission(authn, auth.PermCreateLoans))
+	e.POST("/groups/:group_id/payments", groups.MakeGroupPayment, requirePermission(authn, auth.PermCreatePayments))
+
+	e.GET("/reports/portfolio", reports.Portfolio, requirePermission(authn, auth.PermReadReports))
+	e.GET("/reports/loans.csv", reports.ExportLoans, requirePermission(authn, auth.PermReadReports))
+	e.GET("/collections/due", reports.CollectionsDue, requirePermission(authn, auth.PermReadCollections))
 }
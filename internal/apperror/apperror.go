@@ -0,0 +1,116 @@
+// Package apperror defines the domain errors returned by the usecases. Each
+// carries a kind, which decides the HTTP status, and a stable code clients can
+// match on.
+package apperror
+
+import (
+	"billing/internal/validation"
+	"errors"
+	"fmt"
+)
+
+type Kind int
+
+const (
+	KindNotFound Kind = iota + 1
+	KindConflict
+	KindValidationFailed
+	KindBusinessRuleViolated
+	KindUnauthenticated
+	KindForbidden
+)
+
+// CodeValidationFailed is used for every request rejected by field rules;
+// the individual fields carry their own codes.
+const CodeValidationFailed = "VALIDATION_FAILED"
+
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  validation.Errors
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns a copy of e that also wraps cause, so errors.Is still matches
+// both e and cause.
+func (e *Error) Wrap(cause error) *Error {
+	wrapped := *e
+	wrapped.Err = cause
+	return &wrapped
+}
+
+// WithFields returns a copy of e reporting fields, e.g. every rule a request
+// broke.
+func (e *Error) WithFields(fields validation.Errors) *Error {
+	copied := *e
+	copied.Fields = fields
+	return &copied
+}
+
+// Is matches errors of the same kind and code, so a wrapped copy of a
+// sentinel still satisfies errors.Is against the sentinel.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+func NotFound(code, format string, args ...any) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func Conflict(code, format string, args ...any) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func ValidationFailed(code, format string, args ...any) *Error {
+	return &Error{Kind: KindValidationFailed, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func BusinessRuleViolated(code, format string, args ...any) *Error {
+	return &Error{Kind: KindBusinessRuleViolated, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func Unauthenticated(code, format string, args ...any) *Error {
+	return &Error{Kind: KindUnauthenticated, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func Forbidden(code, format string, args ...any) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Validation wraps field errors found by the validation rules.
+func Validation(fields validation.Errors) *Error {
+	return &Error{
+		Kind:    KindValidationFailed,
+		Code:    CodeValidationFailed,
+		Message: "validation failed",
+		Fields:  fields,
+	}
+}
+
+// From returns err as a domain error, converting bare field errors.
+func From(err error) (*Error, bool) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+
+	var fields validation.Errors
+	if errors.As(err, &fields) {
+		return Validation(fields), true
+	}
+
+	return nil, false
+}
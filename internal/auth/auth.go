@@ -0,0 +1,104 @@
+// Package auth identifies API callers and decides what their roles allow.
+// Internal services authenticate with API keys, back-office users with JWTs.
+package auth
+
+import (
+	"billing/internal/apperror"
+	"context"
+	"slices"
+)
+
+// Roles a caller can hold.
+const (
+	RoleViewer    = "viewer"
+	RoleCollector = "collector"
+	RoleOps       = "ops"
+	// RoleMonitor is for metrics scrapers and grants nothing else.
+	RoleMonitor = "monitor"
+
+	// RoleBorrower is held by borrower-app tokens, whose subject is the
+	// customer ID; such callers only ever see that customer's loans.
+	RoleBorrower = "borrower"
+)
+
+type Permission string
+
+const (
+	PermReadLoans       Permission = "loans:read"
+	PermCreateLoans     Permission = "loans:create"
+	PermCreatePayments  Permission = "payments:create"
+	PermImportPayments  Permission = "payments:import"
+	PermReadReports     Permission = "reports:read"
+	PermReadCollections Permission = "collections:read"
+	PermReadMetrics     Permission = "metrics:read"
+	PermCreateCustomers Permission = "customers:create"
+)
+
+// rolePermissions lists what each role may do. Collectors post payments on any
+// loan and borrowers on their own; only ops register customers and import bank
+// files; viewers read.
+// Staff roles see portfolio reports and collection lists, borrowers do not.
+// Metrics are scraped by monitors and ops.
+var rolePermissions = map[string][]Permission{
+	RoleViewer:    {PermReadLoans, PermReadReports, PermReadCollections},
+	RoleCollector: {PermReadLoans, PermCreatePayments, PermReadReports, PermReadCollections},
+	RoleOps:       {PermReadLoans, PermCreateLoans, PermImportPayments, PermReadReports, PermReadCollections, PermReadMetrics, PermCreateCustomers},
+	RoleBorrower:  {PermReadLoans, PermCreatePayments},
+	RoleMonitor:   {PermReadMetrics},
+}
+
+// Authentication methods recorded on a Principal.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+var (
+	ErrUnauthenticated = apperror.Unauthenticated("UNAUTHENTICATED", "missing or invalid credentials")
+	ErrForbidden       = apperror.Forbidden("FORBIDDEN", "not allowed to perform this action")
+)
+
+// Principal is the authenticated caller.
+type Principal struct {
+	Subject string
+	Roles   []string
+	Method  string
+}
+
+// Can reports whether any of the principal's roles grants perm.
+func (p Principal) Can(perm Permission) bool {
+	for _, role := range p.Roles {
+		if slices.Contains(rolePermissions[role], perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomerScope returns the only customer whose loans the principal may
+// access; ok is false when access is not limited to one customer.
+func (p Principal) CustomerScope() (customerID string, ok bool) {
+	if slices.Contains(p.Roles, RoleBorrower) {
+		return p.Subject, true
+	}
+	return "", false
+}
+
+// IsKnownRole reports whether role is one of the roles above.
+func IsKnownRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the caller stored in ctx; ok is false when the request
+// was not authenticated, e.g. because authentication is disabled.
+func FromContext(ctx context.Context) (p Principal, ok bool) {
+	p, ok = ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
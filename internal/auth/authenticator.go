@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrNoCredentials stops the API from starting with authentication silently
+// off.
+var ErrNoCredentials = errors.New("auth: no API keys or JWT keys configured; set auth.disabled (BILLING_AUTH_DISABLED=true) to run without authentication")
+
+const (
+	HeaderAPIKey = "X-API-Key"
+
+	minHS256SecretLength = 32
+)
+
+// Config lists the accepted credentials. Authentication is enabled as soon as
+// any API key or JWT key is configured; the API refuses to start without any
+// unless Disabled is set.
+type Config struct {
+	APIKeys  []APIKeyConfig `json:"api_keys"`
+	JWT      JWTConfig      `json:"jwt"`
+	Disabled bool           `json:"disabled"`
+}
+
+// APIKeyConfig stores the SHA-256 of a key, never the key itself.
+type APIKeyConfig struct {
+	Name      string   `json:"name"`
+	KeySHA256 string   `json:"key_sha256"`
+	Roles     []string `json:"roles"`
+}
+
+type JWTConfig struct {
+	HS256Secret        string `json:"hs256_secret"`
+	RS256PublicKeyFile string `json:"rs256_public_key_file"`
+	Issuer             string `json:"issuer"`
+	Audience           string `json:"audience"`
+}
+
+func (c Config) Enabled() bool {
+	return len(c.APIKeys) > 0 || c.JWT.HS256Secret != "" || c.JWT.RS256PublicKeyFile != ""
+}
+
+// Validate reports every malformed credential setting, naming fields as
+// they appear under the "auth" section of the service config.
+func (c Config) Validate() error {
+	var errs []error
+	if c.Enabled() && c.Disabled {
+		errs = append(errs, errors.New("auth.disabled: cannot be set together with API keys or JWT keys"))
+	}
+	for i, key := range c.APIKeys {
+		if key.Name == "" {
+			errs = append(errs, fmt.Errorf("auth.api_keys[%d].name: is required", i))
+		}
+		if b, err := hex.DecodeString(key.KeySHA256); err != nil || len(b) != sha256.Size {
+			errs = append(errs, fmt.Errorf("auth.api_keys[%d].key_sha256: must be a hex SHA-256 digest", i))
+		}
+		errs = append(errs, validateRoles(fmt.Sprintf("auth.api_keys[%d].roles", i), key.Roles))
+		if slices.Contains(key.Roles, RoleBorrower) {
+			errs = append(errs, fmt.Errorf("auth.api_keys[%d].roles: %s is only granted by tokens", i, RoleBorrower))
+		}
+	}
+	if s := c.JWT.HS256Secret; s != "" && len(s) < minHS256SecretLength {
+		errs = append(errs, fmt.Errorf("auth.jwt.hs256_secret: must be at least %d bytes", minHS256SecretLength))
+	}
+	return errors.Join(errs...)
+}
+
+func validateRoles(field string, roles []string) error {
+	if len(roles) == 0 {
+		return fmt.Errorf("%s: at least one role is required", field)
+	}
+	for _, role := range roles {
+		if !IsKnownRole(role) {
+			return fmt.Errorf("%s: unknown role %q", field, role)
+		}
+	}
+	return nil
+}
+
+// Authenticator checks request credentials. A nil *Authenticator accepts
+// every request without identifying the caller.
+type Authenticator struct {
+	apiKeys    map[string]Principal
+	hmacSecret []byte
+	rsaKey     *rsa.PublicKey
+	parser     *jwt.Parser
+}
+
+// NewAuthenticator returns nil when cfg explicitly disables authentication,
+// and ErrNoCredentials when it configures no credentials without doing so.
+func NewAuthenticator(cfg Config) (*Authenticator, error) {
+	if !cfg.Enabled() {
+		if cfg.Disabled {
+			return nil, nil
+		}
+		return nil, ErrNoCredentials
+	}
+
+	a := &Authenticator{apiKeys: make(map[string]Principal, len(cfg.APIKeys))}
+	for _, key := range cfg.APIKeys {
+		a.apiKeys[strings.ToLower(key.KeySHA256)] = Principal{
+			Subject: key.Name,
+			Roles:   key.Roles,
+			Method:  MethodAPIKey,
+		}
+	}
+
+	methods := []string{}
+	if cfg.JWT.HS256Secret != "" {
+		a.hmacSecret = []byte(cfg.JWT.HS256Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWT.RS256PublicKeyFile != "" {
+		pem, err := os.ReadFile(cfg.JWT.RS256PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("auth: read RS256 public key: %w", err)
+		}
+		if a.rsaKey, err = jwt.ParseRSAPublicKeyFromPEM(pem); err != nil {
+			return nil, fmt.Errorf("auth: parse RS256 public key: %w", err)
+		}
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if cfg.JWT.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.JWT.Issuer))
+	}
+	if cfg.JWT.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.JWT.Audience))
+	}
+	if len(methods) > 0 {
+		a.parser = jwt.NewParser(opts...)
+	}
+
+	return a, nil
+}
+
+func (a *Authenticator) Enabled() bool {
+	return a != nil
+}
+
+// Authenticate identifies the caller from an X-API-Key header or a bearer
+// token, and fails with ErrUnauthenticated when neither is valid.
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	if key := r.Header.Get(HeaderAPIKey); key != "" {
+		sum := sha256.Sum256([]byte(key))
+		if p, ok := a.apiKeys[hex.EncodeToString(sum[:])]; ok {
+			return p, nil
+		}
+		return Principal{}, ErrUnauthenticated.Wrap(errors.New("unknown API key"))
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" || a.parser == nil {
+		return Principal{}, ErrUnauthenticated
+	}
+	return a.parseToken(token)
+}
+
+type claims struct {
+	Roles []string `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+func (a *Authenticator) parseToken(token string) (Principal, error) {
+	var c claims
+	_, err := a.parser.ParseWithClaims(token, &c, a.key)
+	if err != nil {
+		return Principal{}, ErrUnauthenticated.Wrap(err)
+	}
+	if c.Subject == "" {
+		return Principal{}, ErrUnauthenticated.Wrap(errors.New("token has no subject"))
+	}
+	return Principal{Subject: c.Subject, Roles: c.Roles, Method: MethodJWT}, nil
+}
+
+func (a *Authenticator) key(token *jwt.Token) (any, error) {
+	switch alg := token.Method.Alg(); {
+	case alg == jwt.SigningMethodHS256.Alg() && a.hmacSecret != nil:
+		return a.hmacSecret, nil
+	case alg == jwt.SigningMethodRS256.Alg() && a.rsaKey != nil:
+		return a.rsaKey, nil
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", alg)
+	}
+}
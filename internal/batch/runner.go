@@ -0,0 +1,271 @@
+// Package batch runs the end-of-day job that moves loans between states,
+// charges late penalties and reports what it did.
+package batch
+
+import (
+	"billing/internal/config"
+	"billing/internal/logging"
+	"billing/internal/model"
+	"billing/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	RunStatusRunning   = "RUNNING"
+	RunStatusCompleted = "COMPLETED"
+
+	defaultBatchSize            = 100
+	defaultDelinquencyThreshold = 2
+	defaultPenaltyAmount        = 10000
+	defaultReportDir            = "reports"
+)
+
+type Runner struct {
+	DB                   *gorm.DB
+	BatchSize            int
+	PenaltyAmount        float64
+	ReportDir            string
+	DelinquencyThreshold int
+	// Logger defaults to slog.Default(); every line carries the run ID and
+	// business date.
+	Logger *slog.Logger
+}
+
+// Run processes every unfinished loan for businessDate. Running it again for a
+// date that already completed is a no-op; running it after a crash continues
+// from the checkpoint.
+func (r *Runner) Run(ctx context.Context, businessDate time.Time) (*model.BatchRun, error) {
+	date := repository.StartOfDay(businessDate)
+
+	run, err := r.startRun(ctx, date)
+	if err != nil {
+		r.logger().ErrorContext(ctx, "eod run failed to start", "business_date", date.Format(time.DateOnly), "error", err)
+		return nil, err
+	}
+	logger := r.runLogger(run)
+	if run.Status == RunStatusCompleted {
+		logger.InfoContext(ctx, "eod run already completed")
+		return run, nil
+	}
+
+	for {
+		var loans []model.Loan
+		if err := r.DB.Where("id > ? AND status <> ?", run.LastLoanID, model.LoanStatusCompleted).
+			Order("id").Limit(r.batchSize()).Find(&loans).Error; err != nil {
+			logger.ErrorContext(ctx, "eod failed to list loans", "error", err)
+			return nil, err
+		}
+		if len(loans) == 0 {
+			break
+		}
+
+		for _, loan := range loans {
+			if err := ctx.Err(); err != nil {
+				logger.WarnContext(ctx, "eod run interrupted", "last_loan_id", run.LastLoanID)
+				return run, err
+			}
+			if err := r.processLoan(ctx, run, loan, date); err != nil {
+				logger.ErrorContext(ctx, "eod failed to process loan", "loan_id", loan.ID, "error", err)
+				return run, err
+			}
+		}
+	}
+
+	finishedAt := time.Now()
+	run.Status = RunStatusCompleted
+	run.FinishedAt = &finishedAt
+	if err := r.DB.Save(run).Error; err != nil {
+		logger.ErrorContext(ctx, "eod failed to complete run", "error", err)
+		return nil, err
+	}
+
+	if err := r.writeReport(run); err != nil {
+		logger.ErrorContext(ctx, "eod failed to write report", "error", err)
+		return run, err
+	}
+
+	logger.InfoContext(ctx, "eod run completed",
+		"loans_processed", run.LoansProcessed,
+		"loans_delinquent", run.LoansDelinquent,
+		"status_changes", run.StatusChanges,
+		"penalties_created", run.PenaltiesCreated)
+
+	return run, nil
+}
+
+// Unfinished returns the runs that started but never completed, oldest
+// business date first.
+func (r *Runner) Unfinished(ctx context.Context) ([]model.BatchRun, error) {
+	var runs []model.BatchRun
+	err := r.DB.WithContext(ctx).Where("status = ?", RunStatusRunning).Order("business_date").Find(&runs).Error
+	return runs, err
+}
+
+func (r *Runner) startRun(ctx context.Context, date time.Time) (*model.BatchRun, error) {
+	businessDate := date.Format(time.DateOnly)
+
+	var run model.BatchRun
+	err := r.DB.Where("business_date = ?", businessDate).First(&run).Error
+	if err == nil {
+		if run.Status == RunStatusRunning {
+			r.runLogger(&run).InfoContext(ctx, "eod run resumed", "last_loan_id", run.LastLoanID)
+		}
+		return &run, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	run = model.BatchRun{
+		ID:           uuid.New().String(),
+		BusinessDate: businessDate,
+		Status:       RunStatusRunning,
+		StartedAt:    time.Now(),
+	}
+	if err := r.DB.Create(&run).Error; err != nil {
+		return nil, err
+	}
+
+	return &run, nil
+}
+
+// processLoan applies the day's rules to one loan and advances the checkpoint
+// in the same transaction, so each loan is processed exactly once per run.
+// A bill is overdue once its due date has passed by the end of the business
+// date, the cutoff GetBillStatus uses. Each penalty is added to its bill and
+// to the loan's outstanding, so paying the bill pays the fee.
+func (r *Runner) processLoan(ctx context.Context, run *model.BatchRun, loan model.Loan, date time.Time) error {
+	next := *run
+
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		billings := repository.GormBillingRepository{DB: tx}
+		overdue, err := billings.GetUnpaidDueBy(ctx, loan.ID, date)
+		if err != nil {
+			return err
+		}
+
+		for _, bill := range overdue {
+			penalty := model.Penalty{
+				ID:        uuid.New().String(),
+				LoanID:    loan.ID,
+				BillingID: bill.ID,
+				Amount:    r.penaltyAmount(),
+				Date:      date,
+				CreatedAt: time.Now(),
+			}
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&penalty)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				continue
+			}
+			if err := tx.Model(&model.Billing{}).Where("id = ?", bill.ID).
+				Update("amount", gorm.Expr("amount + ?", penalty.Amount)).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&model.Loan{}).Where("id = ?", loan.ID).
+				Update("outstanding", gorm.Expr("outstanding + ?", penalty.Amount)).Error; err != nil {
+				return err
+			}
+			next.PenaltiesCreated++
+			next.PenaltyAmount += penalty.Amount
+		}
+
+		status := model.LoanStatusInProgress
+		if len(overdue) >= r.delinquencyThreshold() {
+			status = model.LoanStatusDelinquent
+			next.LoansDelinquent++
+		} else if loan.Outstanding <= 0 {
+			status = model.LoanStatusCompleted
+		}
+
+		if status != loan.Status {
+			if err := tx.Model(&model.Loan{}).Where("id = ?", loan.ID).Update("status", status).Error; err != nil {
+				return err
+			}
+			next.StatusChanges++
+		}
+
+		next.LastLoanID = loan.ID
+		next.LoansProcessed++
+
+		return tx.Save(&next).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	*run = next
+	return nil
+}
+
+func (r *Runner) writeReport(run *model.BatchRun) error {
+	dir := r.ReportDir
+	if dir == "" {
+		dir = defaultReportDir
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("eod-%s.json", run.BusinessDate))
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (r *Runner) logger() *slog.Logger {
+	return logging.OrDefault(r.Logger)
+}
+
+func (r *Runner) runLogger(run *model.BatchRun) *slog.Logger {
+	return r.logger().With("run_id", run.ID, "business_date", run.BusinessDate)
+}
+
+func (r *Runner) batchSize() int {
+	if r.BatchSize <= 0 {
+		return defaultBatchSize
+	}
+	return r.BatchSize
+}
+
+func (r *Runner) penaltyAmount() float64 {
+	if r.PenaltyAmount <= 0 {
+		return defaultPenaltyAmount
+	}
+	return r.PenaltyAmount
+}
+
+func (r *Runner) delinquencyThreshold() int {
+	if r.DelinquencyThreshold <= 0 {
+		return defaultDelinquencyThreshold
+	}
+	return r.DelinquencyThreshold
+}
+
+// NewRunner builds a runner with the end-of-day settings from cfg.
+func NewRunner(db *gorm.DB, cfg config.Config, logger *slog.Logger) *Runner {
+	return &Runner{
+		Logger:               logger,
+		DB:                   db,
+		BatchSize:            cfg.EOD.BatchSize,
+		PenaltyAmount:        cfg.EOD.PenaltyAmount,
+		ReportDir:            cfg.EOD.ReportDir,
+		DelinquencyThreshold: cfg.Loan.DelinquencyThreshold,
+	}
+}
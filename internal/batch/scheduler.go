@@ -0,0 +1,134 @@
+package batch
+
+import (
+	"billing/internal/logging"
+	"billing/internal/repository"
+	"billing/internal/util"
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	defaultRunAt = 23*time.Hour + 30*time.Minute
+
+	defaultRetryBackoff = time.Minute
+	maxRetryBackoff     = 15 * time.Minute
+)
+
+// Scheduler triggers the runner once a day, RunAt after local midnight. A run
+// that fails is retried with backoff until its business day is over, and runs
+// left unfinished, e.g. by a restart, are resumed before the next one starts.
+type Scheduler struct {
+	Runner *Runner
+	RunAt  time.Duration
+	// RetryBackoff is the wait before the first retry of a failed run; it
+	// doubles on each further failure, up to 15 minutes.
+	RetryBackoff time.Duration
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Start blocks until ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.resumeUnfinished(ctx)
+
+	for {
+		now := util.GetCurrentTime()
+		next := s.nextRun(now)
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.resumeUnfinished(ctx)
+		s.runWithRetry(ctx, next)
+	}
+}
+
+// resumeUnfinished completes the runs a crash or a failed retry left behind,
+// continuing each from its checkpoint.
+func (s *Scheduler) resumeUnfinished(ctx context.Context) {
+	runs, err := s.Runner.Unfinished(ctx)
+	if err != nil {
+		s.logger().ErrorContext(ctx, "eod failed to list unfinished runs", "error", err)
+		return
+	}
+
+	for _, run := range runs {
+		date, err := time.ParseInLocation(time.DateOnly, run.BusinessDate, time.Local)
+		if err != nil {
+			s.logger().ErrorContext(ctx, "eod skipping run with invalid business date", "run_id", run.ID, "business_date", run.BusinessDate)
+			continue
+		}
+		s.runWithRetry(ctx, date)
+	}
+}
+
+// runWithRetry runs businessDate, retrying failures with exponential backoff
+// until the run completes, the business day ends or ctx is cancelled.
+func (s *Scheduler) runWithRetry(ctx context.Context, businessDate time.Time) {
+	deadline := repository.EndOfDay(businessDate)
+	backoff := s.retryBackoff()
+	logger := s.logger().With("business_date", businessDate.Format(time.DateOnly))
+
+	for {
+		run, err := s.Runner.Run(ctx, businessDate)
+		if err == nil {
+			logger.InfoContext(ctx, "eod scheduled run finished", "run_id", run.ID, "loans_processed", run.LoansProcessed)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		failed := logger
+		if run != nil {
+			failed = logger.With("run_id", run.ID)
+		}
+
+		retryAt := util.GetCurrentTime().Add(backoff)
+		if !retryAt.Before(deadline) {
+			failed.ErrorContext(ctx, "eod scheduled run failed, giving up for the day", "error", err)
+			return
+		}
+		failed.WarnContext(ctx, "eod scheduled run failed, retrying", "retry_in", backoff.String(), "error", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+func (s *Scheduler) logger() *slog.Logger {
+	return logging.OrDefault(s.Logger)
+}
+
+func (s *Scheduler) retryBackoff() time.Duration {
+	if s.RetryBackoff <= 0 {
+		return defaultRetryBackoff
+	}
+	return s.RetryBackoff
+}
+
+func (s *Scheduler) nextRun(now time.Time) time.Time {
+	runAt := s.RunAt
+	if runAt <= 0 {
+		runAt = defaultRunAt
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	next := midnight.Add(runAt)
+	if !next.After(now) {
+		next = midnight.AddDate(0, 0, 1).Add(runAt)
+	}
+	return next
+}
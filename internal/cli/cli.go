@@ -0,0 +1,110 @@
+// Package cli implements the subcommands of the billing binary.
+package cli
+
+import (
+	"billing/internal/batch"
+	"billing/internal/config"
+	"billing/internal/logging"
+	"billing/internal/util"
+	"billing/pkg/db"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const usage = `usage: billing [command]
+
+commands:
+  serve   start the HTTP server (default)
+  eod     run the end-of-day batch job
+  migrate apply (up), roll back (down) or list (status) schema migrations
+  import-payments
+          post the payments in a bank CSV or MT940 file and report each row
+`
+
+// IsCommand reports whether args select a subcommand other than the server.
+// Anything else, such as the flags of a test binary, leaves the server to
+// start.
+func IsCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "eod", "migrate", "import-payments", "help", "-h", "-help", "--help":
+		return true
+	}
+	return false
+}
+
+// Run executes the subcommand in args and returns the process exit code.
+func Run(args []string) int {
+	switch args[0] {
+	case "eod":
+		return runEOD(args[1:], os.Stdout, os.Stderr)
+	case "migrate":
+		return runMigrate(args[1:], os.Stdout, os.Stderr)
+	case "import-payments":
+		return runImportPayments(args[1:], os.Stdout, os.Stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return 0
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+}
+
+func runEOD(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("eod", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	date := fs.String("date", "", "business date to process (YYYY-MM-DD), defaults to today")
+	reportDir := fs.String("report-dir", "", "directory for the run report, overrides eod.report_dir")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	businessDate := util.GetCurrentTime()
+	if *date != "" {
+		parsed, err := time.ParseInLocation(time.DateOnly, *date, time.Local)
+		if err != nil {
+			fmt.Fprintf(stderr, "invalid -date %q: %v\n", *date, err)
+			return 2
+		}
+		businessDate = parsed
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	database, err := db.InitAndMigrate(cfg.DB)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	level, _ := cfg.Log.SlogLevel()
+	runner := batch.NewRunner(database, cfg, logging.New(stderr, level))
+	if *reportDir != "" {
+		runner.ReportDir = *reportDir
+	}
+	run, err := runner.Run(ctx, businessDate)
+	if err != nil {
+		fmt.Fprintln(stderr, "eod failed:", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "eod %s %s: %d loans processed, %d delinquent, %d status changes, %d penalties\n",
+		run.BusinessDate, run.Status, run.LoansProcessed, run.LoansDelinquent, run.StatusChanges, run.PenaltiesCreated)
+	return 0
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"billing/internal/config"
+	"billing/internal/export"
+	"billing/internal/logging"
+	"billing/internal/payfile"
+	"billing/internal/repository"
+	"billing/internal/usecase"
+	"billing/pkg/db"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+func runImportPayments(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("import-payments", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "", "file format, csv or mt940; guessed from the file name by default")
+	reportPath := fs.String("report", "", "write the per-row report as CSV to this file, or - for stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: billing import-payments [-format csv|mt940] [-report file] payment-file")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = payfile.FormatFor(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	rows, err := payfile.Parse(f, *format)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(stderr, "cannot read %s: %v\n", path, err)
+		return 1
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	level, _ := cfg.Log.SlogLevel()
+	logger := logging.New(stderr, level)
+
+	database, err := db.InitAndMigrate(cfg.DB)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	repos := repository.NewGormRepositories(database)
+
+	imports := usecase.PaymentImportUsecase{
+		Loans: &usecase.LoanUsecase{
+			Loans:                repos.Loans,
+			Billings:             repos.Billings,
+			Products:             repos.Products,
+			Customers:            repos.Customers,
+			DelinquencyThreshold: cfg.Loan.DelinquencyThreshold,
+			Logger:               logger,
+		},
+		Imports:        repos.Imports,
+		MaxPaymentLead: cfg.Loan.MaxPaymentLead(),
+		Logger:         logger,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report, err := imports.Import(ctx, filepath.Base(path), rows)
+	if err != nil {
+		fmt.Fprintln(stderr, "import failed:", err)
+		return 1
+	}
+
+	switch *reportPath {
+	case "":
+	case "-":
+		if err := export.WritePaymentImportCSV(stdout, *report); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	default:
+		out, err := os.Create(*reportPath)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		err = export.WritePaymentImportCSV(out, *report)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	// The summary goes to stdout unless the report does.
+	summary := stdout
+	if *reportPath == "-" {
+		summary = stderr
+	}
+	fmt.Fprintf(summary, "import %s: %d rows, %d applied, %d rejected, %d duplicate\n",
+		report.Source, report.Rows, report.Applied, report.Rejected, report.Duplicate)
+	return 0
+}
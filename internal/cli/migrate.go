@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"billing/internal/config"
+	"billing/pkg/db"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func runMigrate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	steps := fs.Int("steps", 1, "number of migrations to roll back with down")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: billing migrate [-steps n] up|down|status")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	conn, err := db.Open(cfg.DB)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	switch fs.Arg(0) {
+	case "up":
+		applied, err := db.MigrateUp(conn)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "applied %d migration(s) %s\n", len(applied), strings.Join(applied, " "))
+	case "down":
+		rolledBack, err := db.MigrateDown(conn, *steps)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "rolled back %d migration(s) %s\n", len(rolledBack), strings.Join(rolledBack, " "))
+	case "status":
+		statuses, err := db.Status(conn)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(stdout, "%s  %-40s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	return 0
+}
@@ -0,0 +1,288 @@
+// Package config loads the service configuration from defaults, an optional
+// JSON file and environment variables, in increasing order of precedence.
+package config
+
+import (
+	"billing/internal/auth"
+	"billing/internal/logging"
+	"billing/internal/tracing"
+	"billing/pkg/db"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+// FileEnv names the environment variable holding the config file path.
+const FileEnv = "BILLING_CONFIG"
+
+type Config struct {
+	Server  ServerConfig   `json:"server"`
+	Log     LogConfig      `json:"log"`
+	Tracing tracing.Config `json:"tracing"`
+	Auth    auth.Config    `json:"auth"`
+	DB      db.Config      `json:"db"`
+	Loan    LoanConfig     `json:"loan"`
+	EOD     EODConfig      `json:"eod"`
+}
+
+type ServerConfig struct {
+	Port                   int `json:"port"`
+	ShutdownTimeoutSeconds int `json:"shutdown_timeout_seconds"`
+}
+
+type LogConfig struct {
+	Level string `json:"level"`
+}
+
+type LoanConfig struct {
+	// DefaultProductCode is the product given to loans created without a
+	// product_code. It is empty by default, so such loans are rejected.
+	DefaultProductCode   string `json:"default_product_code"`
+	DelinquencyThreshold int    `json:"delinquency_threshold"`
+	MaxPaymentLeadDays   int    `json:"max_payment_lead_days"`
+	// RequireRegisteredCustomer rejects loans whose customer_id has no
+	// customer record (register them with POST /customers). It is on by
+	// default; turning it off lets clients that predate the customer table
+	// keep issuing loans to unknown IDs.
+	RequireRegisteredCustomer bool `json:"require_registered_customer"`
+	// MaxActiveLoans and MaxExposure cap what one customer may borrow at
+	// once; zero means no limit.
+	MaxActiveLoans int     `json:"max_active_loans"`
+	MaxExposure    float64 `json:"max_exposure"`
+	// BlockWhenDelinquent refuses new loans to customers with a delinquent
+	// loan.
+	BlockWhenDelinquent bool `json:"block_when_delinquent"`
+}
+
+type EODConfig struct {
+	RunAt         string  `json:"run_at"`
+	PenaltyAmount float64 `json:"penalty_amount"`
+	ReportDir     string  `json:"report_dir"`
+	BatchSize     int     `json:"batch_size"`
+}
+
+// Default returns the configuration used when nothing overrides it.
+func Default() Config {
+	return Config{
+		Server:  ServerConfig{Port: 8000, ShutdownTimeoutSeconds: 15},
+		Log:     LogConfig{Level: "info"},
+		Tracing: tracing.Config{Exporter: tracing.ExporterNone},
+		DB:      db.Config{Driver: db.DriverSQLite, DSN: "amartha.db"},
+		Loan: LoanConfig{
+			DelinquencyThreshold: 2,
+			MaxPaymentLeadDays:   7,
+			BlockWhenDelinquent:  true,
+
+			RequireRegisteredCustomer: true,
+		},
+		EOD: EODConfig{
+			RunAt:         "23:30",
+			PenaltyAmount: 10000,
+			ReportDir:     "reports",
+			BatchSize:     100,
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the file named by
+// BILLING_CONFIG (if set) and BILLING_* environment variables, then
+// validates it. The returned error lists every invalid setting.
+func Load() (Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv(FileEnv); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return cfg, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return cfg, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: read %s: %w", path, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("config: parse %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	var errs []error
+
+	setString := func(name string, dst *string) {
+		if v, ok := os.LookupEnv(name); ok {
+			*dst = v
+		}
+	}
+	setInt := func(name string, dst *int) {
+		if v, ok := os.LookupEnv(name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not an integer", name, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	setBool := func(name string, dst *bool) {
+		if v, ok := os.LookupEnv(name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a boolean", name, v))
+				return
+			}
+			*dst = b
+		}
+	}
+	setFloat := func(name string, dst *float64) {
+		if v, ok := os.LookupEnv(name); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a number", name, v))
+				return
+			}
+			*dst = f
+		}
+	}
+
+	setInt("BILLING_PORT", &c.Server.Port)
+	setInt("BILLING_SHUTDOWN_TIMEOUT_SECONDS", &c.Server.ShutdownTimeoutSeconds)
+	setString("BILLING_LOG_LEVEL", &c.Log.Level)
+	setString("BILLING_TRACING_EXPORTER", &c.Tracing.Exporter)
+	setString("BILLING_TRACING_ENDPOINT", &c.Tracing.Endpoint)
+	setString("BILLING_AUTH_JWT_HS256_SECRET", &c.Auth.JWT.HS256Secret)
+	setString("BILLING_AUTH_JWT_RS256_PUBLIC_KEY_FILE", &c.Auth.JWT.RS256PublicKeyFile)
+	setString("BILLING_AUTH_JWT_ISSUER", &c.Auth.JWT.Issuer)
+	setString("BILLING_AUTH_JWT_AUDIENCE", &c.Auth.JWT.Audience)
+	setBool("BILLING_AUTH_DISABLED", &c.Auth.Disabled)
+	setString("BILLING_DB_DRIVER", &c.DB.Driver)
+	setString("BILLING_DB_DSN", &c.DB.DSN)
+	setString("BILLING_DEFAULT_PRODUCT_CODE", &c.Loan.DefaultProductCode)
+	setInt("BILLING_DELINQUENCY_THRESHOLD", &c.Loan.DelinquencyThreshold)
+	setInt("BILLING_MAX_PAYMENT_LEAD_DAYS", &c.Loan.MaxPaymentLeadDays)
+	setBool("BILLING_REQUIRE_REGISTERED_CUSTOMER", &c.Loan.RequireRegisteredCustomer)
+	setInt("BILLING_MAX_ACTIVE_LOANS", &c.Loan.MaxActiveLoans)
+	setFloat("BILLING_MAX_EXPOSURE", &c.Loan.MaxExposure)
+	setBool("BILLING_BLOCK_WHEN_DELINQUENT", &c.Loan.BlockWhenDelinquent)
+	setString("BILLING_EOD_RUN_AT", &c.EOD.RunAt)
+	setFloat("BILLING_EOD_PENALTY_AMOUNT", &c.EOD.PenaltyAmount)
+	setString("BILLING_EOD_REPORT_DIR", &c.EOD.ReportDir)
+	setInt("BILLING_EOD_BATCH_SIZE", &c.EOD.BatchSize)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Validate reports every setting that is out of range.
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		invalid("server.port: %d is not between 1 and 65535", c.Server.Port)
+	}
+	if c.Server.ShutdownTimeoutSeconds < 1 {
+		invalid("server.shutdown_timeout_seconds: %d must be at least 1", c.Server.ShutdownTimeoutSeconds)
+	}
+
+	if _, err := c.Log.SlogLevel(); err != nil {
+		invalid("log.level: %q is not one of debug, info, warn, error", c.Log.Level)
+	}
+
+	if err := c.Tracing.Validate(); err != nil {
+		invalid("tracing.exporter: %v", err)
+	}
+
+	if err := c.Auth.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch c.DB.Driver {
+	case db.DriverSQLite, db.DriverPostgres:
+	default:
+		invalid("db.driver: %q is not one of %s, %s", c.DB.Driver, db.DriverSQLite, db.DriverPostgres)
+	}
+	if c.DB.DSN == "" {
+		invalid("db.dsn: is required")
+	}
+
+	if c.Loan.DelinquencyThreshold < 1 {
+		invalid("loan.delinquency_threshold: %d must be at least 1", c.Loan.DelinquencyThreshold)
+	}
+	if c.Loan.MaxPaymentLeadDays < 0 {
+		invalid("loan.max_payment_lead_days: %d must not be negative", c.Loan.MaxPaymentLeadDays)
+	}
+	if c.Loan.MaxActiveLoans < 0 {
+		invalid("loan.max_active_loans: %d must not be negative", c.Loan.MaxActiveLoans)
+	}
+	if c.Loan.MaxExposure < 0 {
+		invalid("loan.max_exposure: %g must not be negative", c.Loan.MaxExposure)
+	}
+
+	if _, err := c.EOD.RunAtOffset(); err != nil {
+		invalid("eod.run_at: %v", err)
+	}
+	if c.EOD.PenaltyAmount < 0 {
+		invalid("eod.penalty_amount: %g must not be negative", c.EOD.PenaltyAmount)
+	}
+	if c.EOD.ReportDir == "" {
+		invalid("eod.report_dir: is required")
+	}
+	if c.EOD.BatchSize < 1 {
+		invalid("eod.batch_size: %d must be at least 1", c.EOD.BatchSize)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Address is the listen address for the HTTP server.
+func (s ServerConfig) Address() string {
+	return fmt.Sprintf(":%d", s.Port)
+}
+
+// ShutdownTimeout bounds how long shutdown waits for in-flight work.
+func (s ServerConfig) ShutdownTimeout() time.Duration {
+	return time.Duration(s.ShutdownTimeoutSeconds) * time.Second
+}
+
+// SlogLevel parses Level for the logger.
+func (l LogConfig) SlogLevel() (slog.Level, error) {
+	return logging.ParseLevel(l.Level)
+}
+
+// MaxPaymentLead is how far ahead of today a payment may be dated.
+func (l LoanConfig) MaxPaymentLead() time.Duration {
+	return time.Duration(l.MaxPaymentLeadDays) * 24 * time.Hour
+}
+
+// RunAtOffset parses RunAt ("HH:MM") as an offset from midnight.
+func (e EODConfig) RunAtOffset() (time.Duration, error) {
+	t, err := time.Parse("15:04", e.RunAt)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", e.RunAt)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,78 @@
+// Package csvfile reads the CSV files clients upload: a header row naming the
+// columns, in any order and any case, then one record per line.
+package csvfile
+
+import (
+	"billing/internal/validation"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Record is one data row of a file.
+type Record struct {
+	// Line is the file line the record starts on, counting the header.
+	Line int
+
+	fields []string
+	index  map[string]int
+}
+
+// Field returns the trimmed value of the named column, or "" when the file
+// has no such column or the row is short.
+func (r Record) Field(name string) string {
+	if i, ok := r.index[name]; ok && i < len(r.fields) {
+		return strings.TrimSpace(r.fields[i])
+	}
+	return ""
+}
+
+// Read calls each for every record in r after checking the header names every
+// required column. A byte order mark before the header is ignored. It fails,
+// with a validation error on the "file" field, only when the file as a whole
+// cannot be read; problems with single values are left to each.
+func Read(r io.Reader, required []string, each func(Record)) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return fileError("file is empty")
+	}
+	if err != nil {
+		return fileError("%v", err)
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	var missing []string
+	for _, name := range required {
+		if _, ok := index[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fileError("header is missing column %s", strings.Join(missing, ", "))
+	}
+
+	for {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fileError("%v", err)
+		}
+		line, _ := cr.FieldPos(0)
+		each(Record{Line: line, fields: fields, index: index})
+	}
+}
+
+func fileError(format string, args ...any) error {
+	var errs validation.Errors
+	errs.Add("file", validation.CodeInvalidFormat, format, args...)
+	return errs
+}
@@ -0,0 +1,173 @@
+// Package export renders loans and repayment schedules as files: CSV for
+// tools and pipelines, XLSX for people.
+package export
+
+import (
+	"billing/internal/model"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Formats and their media types.
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+
+	MIMECSV  = "text/csv"
+	MIMEXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// Bill statuses in a schedule.
+const (
+	BillPaid   = "PAID"
+	BillUnpaid = "UNPAID"
+)
+
+// scheduleFirstRow is the spreadsheet row of the first bill, below the loan
+// terms and the column titles.
+const scheduleFirstRow = 10
+
+var scheduleHeader = []string{"loan_id", "sequence", "due_date", "amount", "status", "payment_date"}
+
+// WriteScheduleCSV writes one row per bill of the loan, in sequence order.
+func WriteScheduleCSV(w io.Writer, schedule model.LoanWithBills) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(scheduleHeader); err != nil {
+		return err
+	}
+	for _, b := range schedule.Bills {
+		status, paidOn := billStatus(b)
+		if err := cw.Write([]string{
+			textCell(b.LoanID),
+			strconv.Itoa(b.Sequence),
+			b.DueDate.Format(time.DateOnly),
+			formatAmount(b.Amount),
+			status,
+			paidOn,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteScheduleXLSX writes a workbook with the loan's terms above its bills,
+// keeping amounts and dates as numbers and dates so they can be summed and
+// sorted.
+func WriteScheduleXLSX(w io.Writer, schedule model.LoanWithBills) error {
+	const sheet = "Schedule"
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+
+	loan := schedule.Loan
+	rows := [][]any{
+		{"Loan", loan.ID},
+		{"Customer", loan.CustomerID},
+		{"Amount", loan.Amount},
+		{"Interest rate (%)", loan.InterestRate},
+		{"Total amount", loan.TotalAmount},
+		{"Outstanding", loan.Outstanding},
+		{"Status", loan.Status},
+		{},
+		{"Sequence", "Due date", "Amount", "Status", "Payment date"},
+	}
+	for _, b := range schedule.Bills {
+		status, _ := billStatus(b)
+		var paidOn any
+		if b.PaymentDate != nil {
+			paidOn = *b.PaymentDate
+		}
+		rows = append(rows, []any{b.Sequence, b.DueDate, b.Amount, status, paidOn})
+	}
+
+	dateStyle, err := f.NewStyle(&excelize.Style{NumFmt: 14})
+	if err != nil {
+		return err
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+	if len(schedule.Bills) > 0 {
+		for _, col := range []string{"B", "E"} {
+			if err := f.SetCellStyle(sheet, fmt.Sprintf("%s%d", col, scheduleFirstRow), fmt.Sprintf("%s%d", col, len(rows)), dateStyle); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = f.WriteTo(w)
+	return err
+}
+
+func billStatus(b model.Billing) (status, paidOn string) {
+	if b.PaymentDate == nil {
+		return BillUnpaid, ""
+	}
+	return BillPaid, b.PaymentDate.Format(time.DateOnly)
+}
+
+// textCell keeps a spreadsheet from running a text value as a formula: a value
+// starting with =, +, -, @, tab or CR is prefixed with an apostrophe, which
+// spreadsheets show as text. Numbers and dates are written as they are.
+func textCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+var loanHeader = []string{"id", "customer_id", "product_code", "name", "period", "amount", "interest_rate", "total_amount", "outstanding", "status", "created_at"}
+
+// LoanCSV writes loans one row at a time, so an export of the whole book
+// never holds more than one loan.
+type LoanCSV struct {
+	w *csv.Writer
+}
+
+// NewLoanCSV writes the header row to w.
+func NewLoanCSV(w io.Writer) (*LoanCSV, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(loanHeader); err != nil {
+		return nil, err
+	}
+	return &LoanCSV{w: cw}, nil
+}
+
+func (l *LoanCSV) Write(loan model.ProductLoan) error {
+	return l.w.Write([]string{
+		textCell(loan.ID),
+		textCell(loan.CustomerID),
+		textCell(loan.ProductCode),
+		textCell(loan.Name),
+		strconv.Itoa(loan.Period),
+		formatAmount(loan.Amount),
+		strconv.FormatFloat(loan.InterestRate, 'f', -1, 64),
+		formatAmount(loan.TotalAmount),
+		formatAmount(loan.Outstanding),
+		textCell(loan.Status),
+		loan.CreatedAt.Format(time.RFC3339),
+	})
+}
+
+// Flush sends buffered rows to the underlying writer.
+func (l *LoanCSV) Flush() error {
+	l.w.Flush()
+	return l.w.Error()
+}
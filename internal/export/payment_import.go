@@ -0,0 +1,40 @@
+package export
+
+import (
+	"billing/internal/model"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+var paymentImportHeader = []string{"line", "reference", "loan_id", "amount", "payment_date", "status", "code", "message"}
+
+// WritePaymentImportCSV writes one row per row of the imported file, so the
+// report can be matched back to the bank's lines.
+func WritePaymentImportCSV(w io.Writer, report model.PaymentImportReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(paymentImportHeader); err != nil {
+		return err
+	}
+	for _, r := range report.Results {
+		paymentDate := ""
+		if !r.PaymentDate.IsZero() {
+			paymentDate = r.PaymentDate.Format(time.DateOnly)
+		}
+		if err := cw.Write([]string{
+			strconv.Itoa(r.Line),
+			textCell(r.Reference),
+			textCell(r.LoanID),
+			formatAmount(r.Amount),
+			paymentDate,
+			textCell(r.Status),
+			textCell(r.Code),
+			textCell(r.Message),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
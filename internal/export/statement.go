@@ -0,0 +1,166 @@
+package export
+
+import (
+	"billing/internal/model"
+	"billing/internal/repository"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const MIMEPDF = "application/pdf"
+
+// Bill statuses on a statement, as of its date.
+const (
+	BillDue      = "DUE"
+	BillUpcoming = "UPCOMING"
+)
+
+const (
+	statementFont   = "Helvetica"
+	statementMargin = 15.0
+	rowHeight       = 6.0
+)
+
+var scheduleColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"No.", 15, "R"},
+	{"Due date", 40, "L"},
+	{"Amount", 45, "R"},
+	{"Status", 35, "L"},
+	{"Paid on", 40, "L"},
+}
+
+// WriteStatementPDF renders the statement as an A4 PDF: the loan terms, a
+// summary as of the statement date and the full schedule. The document is
+// dated with the statement date, so the same statement always renders to the
+// same bytes.
+func WriteStatementPDF(w io.Writer, st model.LoanStatement) error {
+	loan := st.Loan
+	pdf := fpdf.New("P", "mm", "A4", "")
+	// Dated by calendar day alone, so the server's time zone does not leak
+	// into the bytes.
+	dated := time.Date(st.AsOf.Year(), st.AsOf.Month(), st.AsOf.Day(), 0, 0, 0, 0, time.UTC)
+	pdf.SetCreationDate(dated)
+	pdf.SetModificationDate(dated)
+	pdf.SetCatalogSort(true)
+	pdf.SetTitle("Loan statement "+loan.ID, false)
+	pdf.SetMargins(statementMargin, statementMargin, statementMargin)
+	pdf.SetAutoPageBreak(true, statementMargin)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-statementMargin + 3)
+		pdf.SetFont(statementFont, "", 8)
+		pdf.CellFormat(0, 5, fmt.Sprintf("Loan %s - page %d of {nb}", loan.ID, pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont(statementFont, "B", 16)
+	pdf.CellFormat(0, 10, "Loan Statement", "", 1, "L", false, 0, "")
+	pdf.SetFont(statementFont, "", 10)
+	pdf.CellFormat(0, rowHeight, "As of "+formatDay(st.AsOf), "", 1, "L", false, 0, "")
+
+	installment := 0.0
+	if len(st.Bills) > 0 {
+		installment = st.Bills[0].Amount
+	}
+	writeSection(pdf, "Loan terms", [][2]string{
+		{"Loan ID", loan.ID},
+		{"Customer ID", loan.CustomerID},
+		{"Product", st.ProductCode},
+		{"Name", loan.Name},
+		{"Disbursed on", formatDay(loan.CreatedAt)},
+		{"Principal", formatRupiah(loan.Amount)},
+		{"Interest rate", fmt.Sprintf("%g%%", loan.InterestRate)},
+		{"Total repayable", formatRupiah(loan.TotalAmount)},
+		{"Installments", fmt.Sprintf("%d x %s", loan.Period, formatRupiah(installment))},
+	})
+
+	delinquency := "Current"
+	if st.IsDelinquent {
+		delinquency = "Delinquent since " + formatDay(*st.DelinquentAt)
+	}
+	writeSection(pdf, "Summary", [][2]string{
+		{"Payments received", fmt.Sprintf("%d of %d", st.PaymentsReceived, len(st.Bills))},
+		{"Total paid", formatRupiah(st.TotalPaid)},
+		{"Outstanding", formatRupiah(st.Outstanding)},
+		{"Delinquency", delinquency},
+	})
+
+	writeHeading(pdf, "Repayment schedule")
+	writeScheduleHeader(pdf)
+	_, pageHeight := pdf.GetPageSize()
+	end := repository.EndOfDay(st.AsOf)
+	for _, b := range st.Bills {
+		if pdf.GetY()+rowHeight > pageHeight-statementMargin {
+			pdf.AddPage()
+			writeScheduleHeader(pdf)
+		}
+		status, paidOn := BillUpcoming, ""
+		switch {
+		case b.PaymentDate != nil && b.PaymentDate.Before(end):
+			status, paidOn = BillPaid, formatDay(*b.PaymentDate)
+		case b.DueDate.Before(end):
+			status = BillDue
+		}
+		cells := []string{fmt.Sprint(b.Sequence), formatDay(b.DueDate), formatRupiah(b.Amount), status, paidOn}
+		for i, col := range scheduleColumns {
+			pdf.CellFormat(col.width, rowHeight, cells[i], "B", 0, col.align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	return pdf.Output(w)
+}
+
+func writeHeading(pdf *fpdf.Fpdf, title string) {
+	pdf.Ln(4)
+	pdf.SetFont(statementFont, "B", 12)
+	pdf.CellFormat(0, 8, title, "B", 1, "L", false, 0, "")
+	pdf.SetFont(statementFont, "", 10)
+}
+
+func writeSection(pdf *fpdf.Fpdf, title string, rows [][2]string) {
+	writeHeading(pdf, title)
+	for _, row := range rows {
+		pdf.CellFormat(50, rowHeight, row[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, rowHeight, row[1], "", 1, "L", false, 0, "")
+	}
+}
+
+func writeScheduleHeader(pdf *fpdf.Fpdf) {
+	pdf.SetFont(statementFont, "B", 10)
+	for _, col := range scheduleColumns {
+		pdf.CellFormat(col.width, rowHeight, col.title, "B", 0, col.align, false, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFont(statementFont, "", 10)
+}
+
+func formatDay(t time.Time) string {
+	return t.Format("02 Jan 2006")
+}
+
+// formatRupiah formats v in whole rupiah with thousands separators, as in
+// "Rp 110,000".
+func formatRupiah(v float64) string {
+	digits := fmt.Sprintf("%.0f", v)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return sign + "Rp " + b.String()
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"billing/internal/repository"
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "billing"
+
+// Payment outcomes recorded by ObservePayment.
+const (
+	OutcomeSuccess            = "success"
+	OutcomeInsufficientAmount = "insufficient_amount"
+	OutcomeNoPendingBill      = "no_pending_bill"
+	OutcomeError              = "error"
+)
+
+// DelinquencySource computes the current delinquent-loan totals at scrape time.
+type DelinquencySource func(ctx context.Context) (repository.DelinquencySummary, error)
+
+// Metrics owns its own registry so several servers can live in one process.
+// A nil *Metrics records nothing.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requestDuration *prometheus.HistogramVec
+	payments        *prometheus.CounterVec
+	paymentAmount   *prometheus.CounterVec
+	loansCreated    prometheus.Counter
+}
+
+func New(delinquency DelinquencySource) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		payments: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "payments_total",
+			Help:      "Payments attempted, by outcome.",
+		}, []string{"outcome"}),
+		paymentAmount: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "payment_amount_total",
+			Help:      "Sum of payment amounts attempted, by outcome.",
+		}, []string{"outcome"}),
+		loansCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "loans_created_total",
+			Help:      "Loans created.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestDuration,
+		m.payments,
+		m.paymentAmount,
+		m.loansCreated,
+	)
+	if delinquency != nil {
+		m.registry.MustRegister(newDelinquencyCollector(delinquency))
+	}
+
+	return m
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.requestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(elapsed.Seconds())
+}
+
+func (m *Metrics) ObservePayment(outcome string, amount float64) {
+	if m == nil {
+		return
+	}
+	m.payments.WithLabelValues(outcome).Inc()
+	m.paymentAmount.WithLabelValues(outcome).Add(amount)
+}
+
+func (m *Metrics) ObserveLoanCreated() {
+	if m == nil {
+		return
+	}
+	m.loansCreated.Inc()
+}
+
+// delinquencyCollector queries the source once per scrape so both gauges
+// describe the same snapshot.
+type delinquencyCollector struct {
+	source      DelinquencySource
+	loans       *prometheus.Desc
+	outstanding *prometheus.Desc
+}
+
+func newDelinquencyCollector(source DelinquencySource) *delinquencyCollector {
+	return &delinquencyCollector{
+		source: source,
+		loans: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "delinquent_loans"),
+			"Loans currently delinquent.", nil, nil),
+		outstanding: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "delinquent_outstanding"),
+			"Outstanding amount of loans currently delinquent.", nil, nil),
+	}
+}
+
+func (c *delinquencyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.loans
+	ch <- c.outstanding
+}
+
+func (c *delinquencyCollector) Collect(ch chan<- prometheus.Metric) {
+	summary, err := c.source(context.Background())
+	if err != nil {
+		slog.Error("summarize delinquency failed", "error", err)
+		ch <- prometheus.NewInvalidMetric(c.loans, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.loans, prometheus.GaugeValue, float64(summary.Loans))
+	ch <- prometheus.MustNewConstMetric(c.outstanding, prometheus.GaugeValue, summary.Outstanding)
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// BatchRun is the checkpoint of an end-of-day run for a single business date.
+// LastLoanID is committed together with each loan's updates so a crashed run
+// resumes after the last fully processed loan.
+type BatchRun struct {
+	ID               string     `json:"id" gorm:"primaryKey"`
+	BusinessDate     string     `json:"business_date" gorm:"uniqueIndex"`
+	Status           string     `json:"status"`
+	LastLoanID       string     `json:"last_loan_id"`
+	LoansProcessed   int        `json:"loans_processed"`
+	LoansDelinquent  int        `json:"loans_delinquent"`
+	StatusChanges    int        `json:"status_changes"`
+	PenaltiesCreated int        `json:"penalties_created"`
+	PenaltyAmount    float64    `json:"penalty_amount"`
+	StartedAt        time.Time  `json:"started_at"`
+	FinishedAt       *time.Time `json:"finished_at"`
+}
+
+// Penalty is a late fee charged once for an overdue bill. Its amount is added
+// to the bill and to the loan's outstanding when it is charged.
+type Penalty struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	LoanID    string    `json:"loan_id" gorm:"index"`
+	BillingID string    `json:"billing_id" gorm:"uniqueIndex"`
+	Amount    float64   `json:"amount"`
+	Date      time.Time `json:"date"`
+	CreatedAt time.Time `json:"created_at"`
+}
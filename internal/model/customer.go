@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// Customer is a borrower. Loans refer to it by CustomerID; only customers whose
+// KYC is verified may take new loans.
+type Customer struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name"`
+	Phone     string    `json:"phone"`
+	KYCStatus string    `json:"kyc_status" gorm:"column:kyc_status"`
+	Branch    string    `json:"branch"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateCustomerRequest registers a borrower. KYCStatus defaults to PENDING.
+type CreateCustomerRequest struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Phone     string `json:"phone"`
+	KYCStatus string `json:"kyc_status"`
+	Branch    string `json:"branch"`
+}
+
+// CustomerLoan is one loan of a customer with its current delinquency.
+type CustomerLoan struct {
+	Loan
+	IsDelinquent bool       `json:"is_delinquent"`
+	DelinquentAt *time.Time `json:"delinquent_at,omitempty"`
+}
+
+type CustomerLoans struct {
+	Customer Customer       `json:"customer"`
+	Loans    []CustomerLoan `json:"loans"`
+}
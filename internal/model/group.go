@@ -0,0 +1,73 @@
+package model
+
+import "time"
+
+// LoanGroup is a group (majelis) of borrowers who meet weekly and are jointly
+// liable for each other's loans.
+type LoanGroup struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name"`
+	Branch    string    `json:"branch"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LoanGroupMember puts a loan in a group; a loan is in at most one.
+type LoanGroupMember struct {
+	LoanID    string `gorm:"primaryKey"`
+	GroupID   string `gorm:"index"`
+	CreatedAt time.Time
+}
+
+type CreateGroupRequest struct {
+	Name    string   `json:"name"`
+	Branch  string   `json:"branch"`
+	LoanIDs []string `json:"loan_ids"`
+}
+
+type AddGroupLoansRequest struct {
+	LoanIDs []string `json:"loan_ids"`
+}
+
+// GroupLoan is one member loan with its delinquency and the amount due on it
+// today.
+type GroupLoan struct {
+	Loan
+	IsDelinquent bool       `json:"is_delinquent"`
+	DelinquentAt *time.Time `json:"delinquent_at,omitempty"`
+	DueAmount    float64    `json:"due_amount"`
+}
+
+// LoanGroupView combines the group's loans. The group is delinquent when any
+// of its loans is.
+type LoanGroupView struct {
+	Group            LoanGroup   `json:"group"`
+	Loans            []GroupLoan `json:"loans"`
+	TotalOutstanding float64     `json:"total_outstanding"`
+	DueAmount        float64     `json:"due_amount"`
+	DelinquentLoans  int         `json:"delinquent_loans"`
+	IsDelinquent     bool        `json:"is_delinquent"`
+}
+
+// GroupPaymentRequest is the lump sum collected at a group's meeting.
+type GroupPaymentRequest struct {
+	PaymentAmount float64   `json:"payment_amount"`
+	PaymentDate   time.Time `json:"payment_date"`
+}
+
+// GroupAllocation is the part of a group payment that paid one bill.
+type GroupAllocation struct {
+	LoanID     string    `json:"loan_id"`
+	CustomerID string    `json:"customer_id"`
+	BillingID  string    `json:"billing_id"`
+	Sequence   int       `json:"sequence"`
+	DueDate    time.Time `json:"due_date"`
+	Amount     float64   `json:"amount"`
+}
+
+type GroupPayment struct {
+	GroupID     string            `json:"group_id"`
+	Amount      float64           `json:"amount"`
+	Date        time.Time         `json:"date"`
+	Allocations []GroupAllocation `json:"allocations"`
+}
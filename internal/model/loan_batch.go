@@ -0,0 +1,28 @@
+package model
+
+// Outcomes of one loan request in a batch.
+const (
+	BatchLoanCreated  = "CREATED"
+	BatchLoanRejected = "REJECTED"
+	BatchLoanFailed   = "FAILED"
+)
+
+// LoanBatchResult is what happened to one loan request of a batch. Row is
+// the request's position in a JSON array, counting from 1, or its line in a
+// CSV file. Code and Message explain rejected and failed rows.
+type LoanBatchResult struct {
+	Row        int    `json:"row"`
+	CustomerID string `json:"customer_id"`
+	Status     string `json:"status"`
+	LoanID     string `json:"loan_id,omitempty"`
+	Code       string `json:"code,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+type LoanBatchReport struct {
+	Rows     int               `json:"rows"`
+	Created  int               `json:"created"`
+	Rejected int               `json:"rejected"`
+	Failed   int               `json:"failed"`
+	Results  []LoanBatchResult `json:"results"`
+}
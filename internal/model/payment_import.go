@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// Outcomes of one row of a payment file.
+const (
+	ImportApplied   = "APPLIED"
+	ImportRejected  = "REJECTED"
+	ImportDuplicate = "DUPLICATE"
+)
+
+// PaymentImportPending marks a reference claimed, by an import older than
+// the transactional one, whose payment was never confirmed. Such a reference
+// is imported again; applied references use ImportApplied.
+const PaymentImportPending = "PENDING"
+
+// PaymentImport records a bank reference taken by an import, so the payment
+// behind it is posted at most once however often the file is imported.
+type PaymentImport struct {
+	Reference   string    `json:"reference" gorm:"primaryKey"`
+	LoanID      string    `json:"loan_id"`
+	Amount      float64   `json:"amount"`
+	PaymentDate time.Time `json:"payment_date"`
+	Status      string    `json:"status"`
+	Source      string    `json:"source"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PaymentImportResult is what happened to one row of a payment file. Code and
+// Message explain rejected and duplicate rows.
+type PaymentImportResult struct {
+	Line        int       `json:"line"`
+	Reference   string    `json:"reference"`
+	LoanID      string    `json:"loan_id"`
+	Amount      float64   `json:"amount"`
+	PaymentDate time.Time `json:"payment_date,omitzero"`
+	Status      string    `json:"status"`
+	Code        string    `json:"code,omitempty"`
+	Message     string    `json:"message,omitempty"`
+}
+
+type PaymentImportReport struct {
+	Source    string                `json:"source"`
+	Rows      int                   `json:"rows"`
+	Applied   int                   `json:"applied"`
+	Rejected  int                   `json:"rejected"`
+	Duplicate int                   `json:"duplicate"`
+	Results   []PaymentImportResult `json:"results"`
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Product is a loan product from the catalogue. Every loan references one
+// and is validated against its limits when it is created. AdminFee is charged
+// once per loan on top of the interest and spread over the installments.
+type Product struct {
+	Code         string    `json:"code" gorm:"primaryKey"`
+	Name         string    `json:"name"`
+	MinAmount    float64   `json:"min_amount"`
+	MaxAmount    float64   `json:"max_amount"`
+	Tenors       []int     `json:"tenors" gorm:"serializer:json;type:text"`
+	InterestRate float64   `json:"interest_rate"`
+	Frequency    string    `json:"frequency"`
+	AdminFee     float64   `json:"admin_fee"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ProductLoan is a loan with the product it was issued under, stored in the
+// loans table. Loan keeps the original API's shape, so only the endpoints that
+// deal with products use this: loan requests, search and exports.
+type ProductLoan struct {
+	Loan
+	ProductCode string `json:"product_code"`
+}
+
+func (ProductLoan) TableName() string {
+	return "loans"
+}
@@ -0,0 +1,67 @@
+package model
+
+import "time"
+
+// ReportPeriod is an inclusive range of days.
+type ReportPeriod struct {
+	From time.Time
+	To   time.Time
+}
+
+// PortfolioReport summarises the loan book for GET /reports/portfolio. Flows
+// (disbursed, collected) cover the period from From to To; balances
+// (outstanding, delinquency, PAR) are as of the end of To.
+type PortfolioReport struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	LoansDisbursed    int64   `json:"loans_disbursed"`
+	TotalDisbursed    float64 `json:"total_disbursed"`
+	PaymentsCollected int64   `json:"payments_collected"`
+	TotalCollected    float64 `json:"total_collected"`
+
+	ActiveLoans           int64   `json:"active_loans"`
+	TotalOutstanding      float64 `json:"total_outstanding"`
+	DelinquentLoans       int64   `json:"delinquent_loans"`
+	DelinquentOutstanding float64 `json:"delinquent_outstanding"`
+
+	// PAR30 and PAR90 are the share of outstanding, between 0 and 1, on loans
+	// with an installment more than 30 or 90 days past due.
+	PAR30 float64 `json:"par30"`
+	PAR90 float64 `json:"par90"`
+}
+
+// DueSearch is a parsed GET /collections/due query.
+type DueSearch struct {
+	Date time.Time
+	// Branch limits the list to customers of one branch; loans of customers
+	// without a record are then left out.
+	Branch string
+	// IncludeOverdue also lists bills that fell due before Date.
+	IncludeOverdue bool
+}
+
+// CollectionsDue lists what field officers should collect on Date.
+type CollectionsDue struct {
+	Date        string               `json:"date"`
+	Branch      string               `json:"branch,omitempty"`
+	Customers   []CollectionCustomer `json:"customers"`
+	TotalAmount float64              `json:"total_amount"`
+}
+
+type CollectionCustomer struct {
+	CustomerID  string           `json:"customer_id"`
+	Name        string           `json:"name"`
+	Phone       string           `json:"phone"`
+	Branch      string           `json:"branch"`
+	Loans       []CollectionLoan `json:"loans"`
+	TotalAmount float64          `json:"total_amount"`
+}
+
+// CollectionLoan holds a loan's payable bills, oldest first; MakePayment takes
+// them in that order.
+type CollectionLoan struct {
+	LoanID         string    `json:"loan_id"`
+	Bills          []Billing `json:"bills"`
+	ExpectedAmount float64   `json:"expected_amount"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// Columns GET /loans can sort by.
+const (
+	LoanSortCreatedAt   = "created_at"
+	LoanSortOutstanding = "outstanding"
+	LoanSortAmount      = "amount"
+)
+
+// LoanSearch is a parsed GET /loans query. Nil and empty fields do not filter.
+type LoanSearch struct {
+	Status      string
+	CustomerID  string
+	ProductCode string
+	// CreatedFrom and CreatedTo bound created_at by day, both inclusive.
+	CreatedFrom    *time.Time
+	CreatedTo      *time.Time
+	Delinquent     *bool
+	MinOutstanding *float64
+	MaxOutstanding *float64
+
+	Sort  string
+	Desc  bool
+	Limit int
+	// Cursor is the next_cursor of the previous page.
+	Cursor string
+}
+
+// LoanPage is one page of GET /loans. NextCursor is empty on the last page.
+type LoanPage struct {
+	Loans      []ProductLoan `json:"loans"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
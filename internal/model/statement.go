@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// LoanStatement is a loan's schedule with what had been paid, what was owed
+// and whether the loan was delinquent at the end of AsOf.
+type LoanStatement struct {
+	LoanWithBills
+	ProductCode string
+	AsOf        time.Time
+
+	PaymentsReceived int
+	TotalPaid        float64
+	Outstanding      float64
+	IsDelinquent     bool
+	DelinquentAt     *time.Time
+}
@@ -0,0 +1,15 @@
+package model
+
+// Loan statuses.
+const (
+	LoanStatusInProgress = "IN_PROGRESS"
+	LoanStatusDelinquent = "DELINQUENT"
+	LoanStatusCompleted  = "COMPLETED"
+)
+
+// Customer KYC statuses.
+const (
+	KYCStatusPending  = "PENDING"
+	KYCStatusVerified = "VERIFIED"
+	KYCStatusRejected = "REJECTED"
+)
@@ -0,0 +1,211 @@
+// Package payfile reads the payment files banks send for reconciliation: CSV
+// exports and MT940 statements.
+package payfile
+
+import (
+	"billing/internal/csvfile"
+	"billing/internal/validation"
+	"bufio"
+	"io"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported file formats.
+const (
+	FormatCSV   = "csv"
+	FormatMT940 = "mt940"
+)
+
+// Row is one payment read from a file. A row the file got wrong carries its
+// field errors in Err and is reported rather than applied.
+type Row struct {
+	Line        int
+	Reference   string
+	LoanID      string
+	Amount      float64
+	PaymentDate time.Time
+	Err         validation.Errors
+}
+
+// FormatFor guesses a file's format from its name: .sta, .940 and .mt940 are
+// MT940 statements, anything else is read as CSV.
+func FormatFor(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".sta", ".940", ".mt940":
+		return FormatMT940
+	default:
+		return FormatCSV
+	}
+}
+
+// Parse reads every payment in r. It fails only when the file as a whole
+// cannot be read; problems with single rows are left on the rows.
+func Parse(r io.Reader, format string) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r)
+	case FormatMT940:
+		return parseMT940(r)
+	default:
+		var errs validation.Errors
+		errs.Add("format", validation.CodeNotAllowed, "format must be one of %s, %s", FormatCSV, FormatMT940)
+		return nil, errs
+	}
+}
+
+var csvColumns = []string{"reference", "loan_id", "amount", "payment_date"}
+
+// parseCSV reads a file with a header row naming at least csvColumns, in any
+// order. payment_date is YYYY-MM-DD or RFC 3339.
+func parseCSV(r io.Reader) ([]Row, error) {
+	var rows []Row
+	err := csvfile.Read(r, csvColumns, func(rec csvfile.Record) {
+		row := Row{Line: rec.Line, Reference: rec.Field("reference"), LoanID: rec.Field("loan_id")}
+		if row.Reference == "" {
+			row.Err.Add("reference", validation.CodeRequired, "reference is required")
+		}
+		row.Amount = parseAmount(&row.Err, rec.Field("amount"))
+		row.PaymentDate = parseDate(&row.Err, rec.Field("payment_date"))
+		rows = append(rows, row)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+var (
+	mt940Tag = regexp.MustCompile(`^:(\d{2}[A-Z]?):`)
+	// mt940Entry matches a :61: statement line: value date, optional entry
+	// date, debit/credit mark, optional funds code, amount, transaction type,
+	// the account owner's reference and the bank's after "//".
+	mt940Entry = regexp.MustCompile(`^(\d{6})(?:\d{4})?(R?[CD])[A-Z]?(\d+(?:,\d*)?)[NSF][A-Z0-9]{3}([^/]*)(?://(\S*))?`)
+	// mt940Loan finds the loan in a :86: narrative, e.g. "/LOAN/ABC123/".
+	mt940Loan = regexp.MustCompile(`/LOAN/([A-Za-z0-9_-]+)`)
+)
+
+// parseMT940 reads the :61: entries of an MT940 statement. Each entry's loan
+// comes from the /LOAN/ code in the :86: narrative that follows it, and its
+// reference is the bank's reference, or the account owner's when the bank
+// gave none. Only credits are payments.
+func parseMT940(r io.Reader) ([]Row, error) {
+	var rows []Row
+	var entry *Row
+	var narrative strings.Builder
+	inNarrative := false
+
+	finish := func() {
+		if entry == nil {
+			return
+		}
+		if m := mt940Loan.FindStringSubmatch(narrative.String()); m != nil {
+			entry.LoanID = m[1]
+		} else {
+			entry.Err.Add("loan_id", validation.CodeRequired, "no /LOAN/ code in the :86: narrative")
+		}
+		rows = append(rows, *entry)
+		entry = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		tag := mt940Tag.FindStringSubmatch(text)
+		if tag == nil {
+			if inNarrative {
+				narrative.WriteString(text)
+			}
+			continue
+		}
+		body := text[len(tag[0]):]
+		inNarrative = false
+
+		switch tag[1] {
+		case "61":
+			finish()
+			entry = parseEntry(line, body)
+			narrative.Reset()
+		case "86":
+			if entry != nil {
+				inNarrative = true
+				narrative.WriteString(body)
+			}
+		default:
+			finish()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fileError("%v", err)
+	}
+	finish()
+	return rows, nil
+}
+
+func parseEntry(line int, body string) *Row {
+	row := &Row{Line: line}
+	m := mt940Entry.FindStringSubmatch(body)
+	if m == nil {
+		row.Err.Add("entry", validation.CodeInvalidFormat, "cannot read :61: statement line")
+		return row
+	}
+
+	date, err := time.ParseInLocation("060102", m[1], time.Local)
+	if err != nil {
+		row.Err.Add("payment_date", validation.CodeInvalidFormat, "value date %q is not a valid YYMMDD date", m[1])
+	}
+	row.PaymentDate = date
+
+	row.Reference = strings.TrimSpace(m[5])
+	if row.Reference == "" {
+		row.Reference = strings.TrimSpace(m[4])
+	}
+	if row.Reference == "" || row.Reference == "NONREF" {
+		row.Reference = ""
+		row.Err.Add("reference", validation.CodeRequired, "entry has no reference")
+	}
+
+	row.Amount = parseAmount(&row.Err, strings.Replace(m[3], ",", ".", 1))
+	if m[2] != "C" {
+		row.Err.Add("amount", validation.CodeNotAllowed, "only credit entries are payments")
+	}
+	return row
+}
+
+func parseAmount(errs *validation.Errors, v string) float64 {
+	if v == "" {
+		errs.Add("amount", validation.CodeRequired, "amount is required")
+		return 0
+	}
+	amount, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		errs.Add("amount", validation.CodeInvalidFormat, "amount %q is not a number", v)
+		return 0
+	}
+	return amount
+}
+
+func parseDate(errs *validation.Errors, v string) time.Time {
+	if v == "" {
+		errs.Add("payment_date", validation.CodeRequired, "payment_date is required")
+		return time.Time{}
+	}
+	if date, err := time.ParseInLocation(time.DateOnly, v, time.Local); err == nil {
+		return date
+	}
+	if date, err := time.Parse(time.RFC3339, v); err == nil {
+		return date
+	}
+	errs.Add("payment_date", validation.CodeInvalidFormat, "payment_date %q must be YYYY-MM-DD or RFC 3339", v)
+	return time.Time{}
+}
+
+func fileError(format string, args ...any) error {
+	var errs validation.Errors
+	errs.Add("file", validation.CodeInvalidFormat, format, args...)
+	return errs
+}
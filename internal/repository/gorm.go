@@ -0,0 +1,467 @@
+package repository
+
+import (
+	"billing/internal/model"
+	"billing/pkg/db"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NewGormRepositories returns repositories backed by db.
+func NewGormRepositories(db *gorm.DB) Repositories {
+	return Repositories{
+		Loans:     &GormLoanRepository{DB: db},
+		Billings:  &GormBillingRepository{DB: db},
+		Products:  &GormProductRepository{DB: db},
+		Customers: &GormCustomerRepository{DB: db},
+		Reports:   &GormReportRepository{DB: db},
+		Imports:   &GormPaymentImportRepository{DB: db},
+		Groups:    &GormGroupRepository{DB: db},
+		Health:    &GormHealthChecker{DB: db},
+	}
+}
+
+// GormHealthChecker is ready once the database answers and is fully migrated.
+type GormHealthChecker struct {
+	DB *gorm.DB
+}
+
+func (h *GormHealthChecker) Ready(ctx context.Context) error {
+	return db.CheckReady(ctx, h.DB)
+}
+
+type GormLoanRepository struct {
+	DB *gorm.DB
+}
+
+func (r *GormLoanRepository) Create(ctx context.Context, loan *model.ProductLoan) error {
+	return r.DB.WithContext(ctx).Save(loan).Error
+}
+
+// billInsertBatch keeps each INSERT of bills under the drivers' limits on
+// bound parameters.
+const billInsertBatch = 500
+
+func (r *GormLoanRepository) CreateBatch(ctx context.Context, loans []model.ProductLoan, bills []model.Billing) error {
+	if len(loans) == 0 {
+		return nil
+	}
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(loans, len(loans)).Error; err != nil {
+			return err
+		}
+		if len(bills) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(bills, billInsertBatch).Error
+	})
+}
+
+func (r *GormLoanRepository) GetByID(ctx context.Context, id string) (*model.Loan, error) {
+	var loan model.Loan
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&loan).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &loan, nil
+}
+
+func (r *GormLoanRepository) ProductCode(ctx context.Context, loanID string) (string, error) {
+	var loan model.ProductLoan
+	if err := r.DB.WithContext(ctx).Where("id = ?", loanID).First(&loan).Error; err != nil {
+		return "", notFound(err)
+	}
+	return loan.ProductCode, nil
+}
+
+func (r *GormLoanRepository) ListByCustomerID(ctx context.Context, customerID string) ([]model.Loan, error) {
+	var loans []model.Loan
+	if err := r.DB.WithContext(ctx).Where("customer_id = ?", customerID).Order("created_at, id").Find(&loans).Error; err != nil {
+		return nil, err
+	}
+	return loans, nil
+}
+
+// loanSortColumns keeps sort names from ever reaching SQL unchecked.
+var loanSortColumns = map[string]string{
+	model.LoanSortCreatedAt:   "created_at",
+	model.LoanSortOutstanding: "outstanding",
+	model.LoanSortAmount:      "amount",
+}
+
+func (r *GormLoanRepository) Search(ctx context.Context, q LoanQuery) ([]model.ProductLoan, error) {
+	var loans []model.ProductLoan
+	err := r.query(ctx, q).Limit(q.Search.Limit).Find(&loans).Error
+	return loans, err
+}
+
+func (r *GormLoanRepository) Each(ctx context.Context, q LoanQuery, fn func(model.ProductLoan) error) error {
+	tx := r.query(ctx, q)
+	rows, err := tx.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var loan model.ProductLoan
+		if err := tx.ScanRows(rows, &loan); err != nil {
+			return err
+		}
+		if err := fn(loan); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// query selects the loans matching q after q.After, in sort order.
+func (r *GormLoanRepository) query(ctx context.Context, q LoanQuery) *gorm.DB {
+	s := q.Search
+	tx := r.DB.WithContext(ctx).Model(&model.ProductLoan{})
+	if s.Status != "" {
+		tx = tx.Where("status = ?", s.Status)
+	}
+	if s.CustomerID != "" {
+		tx = tx.Where("customer_id = ?", s.CustomerID)
+	}
+	if s.ProductCode != "" {
+		tx = tx.Where("product_code = ?", s.ProductCode)
+	}
+	if s.CreatedFrom != nil {
+		tx = tx.Where("created_at >= ?", StartOfDay(*s.CreatedFrom))
+	}
+	if s.CreatedTo != nil {
+		tx = tx.Where("created_at < ?", EndOfDay(*s.CreatedTo))
+	}
+	if s.MinOutstanding != nil {
+		tx = tx.Where("outstanding >= ?", *s.MinOutstanding)
+	}
+	if s.MaxOutstanding != nil {
+		tx = tx.Where("outstanding <= ?", *s.MaxOutstanding)
+	}
+	if s.Delinquent != nil {
+		overdue := r.DB.Model(&model.Billing{}).Select("loan_id").
+			Where("payment_date IS NULL AND due_date < ?", q.DelinquentBefore).
+			Group("loan_id").Having("COUNT(*) >= ?", q.MinOverdue)
+		if *s.Delinquent {
+			tx = tx.Where("id IN (?)", overdue)
+		} else {
+			tx = tx.Where("id NOT IN (?)", overdue)
+		}
+	}
+
+	column, ok := loanSortColumns[s.Sort]
+	if !ok {
+		column = "created_at"
+	}
+	op, dir := ">", "ASC"
+	if s.Desc {
+		op, dir = "<", "DESC"
+	}
+	if c := q.After; c != nil {
+		tx = tx.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, op), c.key(), c.key(), c.ID)
+	}
+	return tx.Order(fmt.Sprintf("%s %s, id %s", column, dir, dir))
+}
+
+// ApplyPayments only pays bills still unpaid, so of two requests racing for
+// the same bill one gets ErrConflict. The status is decided on the stored
+// outstanding, not on the one the caller read.
+func (r *GormLoanRepository) ApplyPayments(ctx context.Context, payments []BillPayment) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return applyPayments(tx, payments)
+	})
+}
+
+func applyPayments(tx *gorm.DB, payments []BillPayment) error {
+	for _, p := range payments {
+		res := tx.Model(&model.Billing{}).Where("id = ? AND loan_id = ? AND payment_date IS NULL", p.BillingID, p.LoanID).
+			Update("payment_date", p.PaymentDate)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != 1 {
+			return ErrConflict
+		}
+
+		if err := tx.Model(&model.Loan{}).Where("id = ?", p.LoanID).Updates(map[string]interface{}{
+			"outstanding": gorm.Expr("outstanding - ?", p.Amount),
+			"status":      gorm.Expr("CASE WHEN outstanding - ? <= 0 THEN ? ELSE ? END", p.Amount, model.LoanStatusCompleted, model.LoanStatusInProgress),
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *GormLoanRepository) SummarizeDelinquency(ctx context.Context, before time.Time, minOverdue int) (DelinquencySummary, error) {
+	overdue := r.DB.Model(&model.Billing{}).Select("loan_id").
+		Where("payment_date IS NULL AND due_date < ?", before).
+		Group("loan_id").Having("COUNT(*) >= ?", minOverdue)
+
+	var summary DelinquencySummary
+	err := r.DB.WithContext(ctx).Model(&model.Loan{}).
+		Select("COUNT(*) AS loans, COALESCE(SUM(outstanding), 0) AS outstanding").
+		Where("id IN (?)", overdue).
+		Scan(&summary).Error
+	return summary, err
+}
+
+type GormBillingRepository struct {
+	DB *gorm.DB
+}
+
+func (r *GormBillingRepository) CreateBatch(ctx context.Context, bills []model.Billing) error {
+	return r.DB.WithContext(ctx).Save(&bills).Error
+}
+
+func (r *GormBillingRepository) GetByLoanID(ctx context.Context, loanID string) ([]model.Billing, error) {
+	var bills []model.Billing
+	if err := r.DB.WithContext(ctx).Where("loan_id = ?", loanID).Order("sequence").Find(&bills).Error; err != nil {
+		return nil, err
+	}
+	return bills, nil
+}
+
+func (r *GormBillingRepository) GetUnpaidDueBy(ctx context.Context, loanID string, date time.Time) ([]model.Billing, error) {
+	var bills []model.Billing
+	if err := r.DB.WithContext(ctx).Where("loan_id = ? AND due_date < ? AND payment_date IS NULL", loanID, EndOfDay(date)).
+		Order("due_date").Find(&bills).Error; err != nil {
+		return nil, err
+	}
+	return bills, nil
+}
+
+type GormProductRepository struct {
+	DB *gorm.DB
+}
+
+func (r *GormProductRepository) CreateIfMissing(ctx context.Context, product *model.Product) error {
+	return r.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(product).Error
+}
+
+func (r *GormProductRepository) GetByCode(ctx context.Context, code string) (*model.Product, error) {
+	var product model.Product
+	if err := r.DB.WithContext(ctx).Where("code = ?", code).First(&product).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &product, nil
+}
+
+type GormCustomerRepository struct {
+	DB *gorm.DB
+}
+
+func (r *GormCustomerRepository) Create(ctx context.Context, customer *model.Customer) error {
+	res := r.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(customer)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrDuplicate
+	}
+	return nil
+}
+
+func (r *GormCustomerRepository) GetByID(ctx context.Context, id string) (*model.Customer, error) {
+	var customer model.Customer
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&customer).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &customer, nil
+}
+
+type GormReportRepository struct {
+	DB *gorm.DB
+}
+
+// Portfolio derives balances from the bills, since loans only record their
+// current outstanding: a loan's outstanding at To is the sum of its bills not
+// yet paid by then.
+func (r *GormReportRepository) Portfolio(ctx context.Context, q PortfolioQuery) (PortfolioTotals, error) {
+	db := r.DB.WithContext(ctx)
+	var totals PortfolioTotals
+
+	var disbursed struct {
+		Loans  int64
+		Amount float64
+	}
+	if err := db.Model(&model.Loan{}).Select("COUNT(*) AS loans, COALESCE(SUM(amount), 0) AS amount").
+		Where("created_at >= ? AND created_at < ?", q.From, q.To).Scan(&disbursed).Error; err != nil {
+		return totals, err
+	}
+	totals.LoansDisbursed, totals.Disbursed = disbursed.Loans, disbursed.Amount
+
+	var collected struct {
+		Payments int64
+		Amount   float64
+	}
+	if err := db.Model(&model.Billing{}).Select("COUNT(*) AS payments, COALESCE(SUM(amount), 0) AS amount").
+		Where("payment_date >= ? AND payment_date < ?", q.From, q.To).Scan(&collected).Error; err != nil {
+		return totals, err
+	}
+	totals.Payments, totals.Collected = collected.Payments, collected.Amount
+
+	unpaid := func() *gorm.DB {
+		return r.DB.Model(&model.Billing{}).Where("created_at < ? AND (payment_date IS NULL OR payment_date >= ?)", q.To, q.To)
+	}
+	balance := func(loanIDs *gorm.DB) (DelinquencySummary, error) {
+		var sum DelinquencySummary
+		tx := unpaid().WithContext(ctx).Select("COUNT(DISTINCT loan_id) AS loans, COALESCE(SUM(amount), 0) AS outstanding")
+		if loanIDs != nil {
+			tx = tx.Where("loan_id IN (?)", loanIDs)
+		}
+		err := tx.Scan(&sum).Error
+		return sum, err
+	}
+	pastDue := func(days int) *gorm.DB {
+		return unpaid().Select("loan_id").Where("due_date < ?", q.To.AddDate(0, 0, -days))
+	}
+
+	active, err := balance(nil)
+	if err != nil {
+		return totals, err
+	}
+	totals.ActiveLoans, totals.Outstanding = active.Loans, active.Outstanding
+
+	delinquent, err := balance(unpaid().Select("loan_id").Where("due_date < ?", q.To).
+		Group("loan_id").Having("COUNT(*) >= ?", q.MinOverdue))
+	if err != nil {
+		return totals, err
+	}
+	totals.DelinquentLoans, totals.DelinquentOutstanding = delinquent.Loans, delinquent.Outstanding
+
+	par30, err := balance(pastDue(30))
+	if err != nil {
+		return totals, err
+	}
+	par90, err := balance(pastDue(90))
+	if err != nil {
+		return totals, err
+	}
+	totals.Par30Outstanding, totals.Par90Outstanding = par30.Outstanding, par90.Outstanding
+
+	return totals, nil
+}
+
+func (r *GormReportRepository) ListDue(ctx context.Context, q DueQuery) ([]DueBill, error) {
+	tx := r.DB.WithContext(ctx).Table("billings").
+		Select("billings.*, loans.customer_id, "+
+			"COALESCE(customers.name, '') AS customer_name, "+
+			"COALESCE(customers.phone, '') AS customer_phone, "+
+			"COALESCE(customers.branch, '') AS customer_branch").
+		Joins("JOIN loans ON loans.id = billings.loan_id").
+		Joins("LEFT JOIN customers ON customers.id = loans.customer_id").
+		Where("billings.payment_date IS NULL AND billings.due_date < ?", q.Before)
+	if !q.From.IsZero() {
+		tx = tx.Where("billings.due_date >= ?", q.From)
+	}
+	if q.Branch != "" {
+		tx = tx.Where("customers.branch = ?", q.Branch)
+	}
+
+	var bills []DueBill
+	err := tx.Order("loans.customer_id, billings.loan_id, billings.due_date").Scan(&bills).Error
+	return bills, err
+}
+
+type GormPaymentImportRepository struct {
+	DB *gorm.DB
+}
+
+func (r *GormPaymentImportRepository) Applied(ctx context.Context, reference string) (bool, error) {
+	var count int64
+	err := r.DB.WithContext(ctx).Model(&model.PaymentImport{}).
+		Where("reference = ? AND status <> ?", reference, model.PaymentImportPending).Count(&count).Error
+	return count > 0, err
+}
+
+// Apply inserts the reference before paying, so of two imports racing for it
+// the second waits on the primary key and then gets ErrDuplicate.
+func (r *GormPaymentImportRepository) Apply(ctx context.Context, rec *model.PaymentImport, payments []BillPayment) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("reference = ? AND status = ?", rec.Reference, model.PaymentImportPending).
+			Delete(&model.PaymentImport{}).Error; err != nil {
+			return err
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(rec)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrDuplicate
+		}
+		return applyPayments(tx, payments)
+	})
+}
+
+type GormGroupRepository struct {
+	DB *gorm.DB
+}
+
+func (r *GormGroupRepository) Create(ctx context.Context, group *model.LoanGroup, members []model.LoanGroupMember) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(group).Error; err != nil {
+			return err
+		}
+		return addMembers(tx, members)
+	})
+}
+
+func (r *GormGroupRepository) GetByID(ctx context.Context, id string) (*model.LoanGroup, error) {
+	var group model.LoanGroup
+	if err := r.DB.WithContext(ctx).Where("id = ?", id).First(&group).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &group, nil
+}
+
+func (r *GormGroupRepository) AddMembers(ctx context.Context, members []model.LoanGroupMember) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return addMembers(tx, members)
+	})
+}
+
+// addMembers inserts members, skipping loans that are in a group already, and
+// fails with ErrDuplicate if it skipped any so the transaction rolls back. Of
+// two requests racing to group the same loan, the second waits on the primary
+// key and then gets ErrDuplicate.
+func addMembers(tx *gorm.DB, members []model.LoanGroupMember) error {
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&members)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected < int64(len(members)) {
+		return ErrDuplicate
+	}
+	return nil
+}
+
+func (r *GormGroupRepository) GroupOf(ctx context.Context, loanID string) (string, error) {
+	var member model.LoanGroupMember
+	if err := r.DB.WithContext(ctx).Where("loan_id = ?", loanID).First(&member).Error; err != nil {
+		return "", notFound(err)
+	}
+	return member.GroupID, nil
+}
+
+func (r *GormGroupRepository) ListLoans(ctx context.Context, groupID string) ([]model.Loan, error) {
+	var loans []model.Loan
+	err := r.DB.WithContext(ctx).Select("loans.*").
+		Joins("JOIN loan_group_members ON loan_group_members.loan_id = loans.id").
+		Where("loan_group_members.group_id = ?", groupID).
+		Order("loan_group_members.created_at, loans.id").Find(&loans).Error
+	return loans, err
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,543 @@
+package repository
+
+import (
+	"billing/internal/model"
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewMemoryRepositories returns repositories that keep everything in memory.
+// They share nothing between calls, so each call starts with an empty store.
+func NewMemoryRepositories() Repositories {
+	billings := &MemoryBillingRepository{}
+	loans := &MemoryLoanRepository{loans: map[string]model.ProductLoan{}, billings: billings}
+	customers := &MemoryCustomerRepository{customers: map[string]model.Customer{}}
+	return Repositories{
+		Loans:     loans,
+		Billings:  billings,
+		Products:  &MemoryProductRepository{products: map[string]model.Product{}},
+		Customers: customers,
+		Reports:   &MemoryReportRepository{loans: loans, billings: billings, customers: customers},
+		Imports:   &MemoryPaymentImportRepository{imports: map[string]model.PaymentImport{}, loans: loans},
+		Groups:    &MemoryGroupRepository{groups: map[string]model.LoanGroup{}, members: map[string]model.LoanGroupMember{}, loans: loans},
+		Health:    MemoryHealthChecker{},
+	}
+}
+
+// MemoryHealthChecker is always ready.
+type MemoryHealthChecker struct{}
+
+func (MemoryHealthChecker) Ready(context.Context) error {
+	return nil
+}
+
+type MemoryLoanRepository struct {
+	mu       sync.RWMutex
+	loans    map[string]model.ProductLoan
+	billings *MemoryBillingRepository
+}
+
+func (r *MemoryLoanRepository) Create(_ context.Context, loan *model.ProductLoan) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loans[loan.ID] = *loan
+	return nil
+}
+
+func (r *MemoryLoanRepository) CreateBatch(ctx context.Context, loans []model.ProductLoan, bills []model.Billing) error {
+	r.mu.Lock()
+	for _, loan := range loans {
+		r.loans[loan.ID] = loan
+	}
+	r.mu.Unlock()
+	return r.billings.CreateBatch(ctx, bills)
+}
+
+func (r *MemoryLoanRepository) GetByID(_ context.Context, id string) (*model.Loan, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	loan, ok := r.loans[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &loan.Loan, nil
+}
+
+func (r *MemoryLoanRepository) ProductCode(_ context.Context, loanID string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	loan, ok := r.loans[loanID]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return loan.ProductCode, nil
+}
+
+func (r *MemoryLoanRepository) ListByCustomerID(_ context.Context, customerID string) ([]model.Loan, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var loans []model.Loan
+	for _, loan := range r.loans {
+		if loan.CustomerID == customerID {
+			loans = append(loans, loan.Loan)
+		}
+	}
+	slices.SortFunc(loans, func(a, b model.Loan) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return loans, nil
+}
+
+func (r *MemoryLoanRepository) Search(_ context.Context, q LoanQuery) ([]model.ProductLoan, error) {
+	loans := r.query(q)
+	if len(loans) > q.Search.Limit {
+		loans = loans[:q.Search.Limit]
+	}
+	return loans, nil
+}
+
+func (r *MemoryLoanRepository) Each(_ context.Context, q LoanQuery, fn func(model.ProductLoan) error) error {
+	for _, loan := range r.query(q) {
+		if err := fn(loan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// query returns the loans matching q after q.After, in sort order.
+func (r *MemoryLoanRepository) query(q LoanQuery) []model.ProductLoan {
+	s := q.Search
+	var overdue map[string]int
+	if s.Delinquent != nil {
+		overdue = map[string]int{}
+		for _, b := range r.billings.filter(func(b model.Billing) bool {
+			return b.PaymentDate == nil && b.DueDate.Before(q.DelinquentBefore)
+		}) {
+			overdue[b.LoanID]++
+		}
+	}
+
+	r.mu.RLock()
+	var loans []model.ProductLoan
+	for _, loan := range r.loans {
+		switch {
+		case s.Status != "" && loan.Status != s.Status,
+			s.CustomerID != "" && loan.CustomerID != s.CustomerID,
+			s.ProductCode != "" && loan.ProductCode != s.ProductCode,
+			s.CreatedFrom != nil && loan.CreatedAt.Before(StartOfDay(*s.CreatedFrom)),
+			s.CreatedTo != nil && !loan.CreatedAt.Before(EndOfDay(*s.CreatedTo)),
+			s.MinOutstanding != nil && loan.Outstanding < *s.MinOutstanding,
+			s.MaxOutstanding != nil && loan.Outstanding > *s.MaxOutstanding,
+			s.Delinquent != nil && (overdue[loan.ID] >= q.MinOverdue) != *s.Delinquent:
+			continue
+		}
+		loans = append(loans, loan)
+	}
+	r.mu.RUnlock()
+
+	compare := func(a, b model.ProductLoan) int {
+		ca, cb := CursorAfter(a.Loan, s.Sort, s.Desc), CursorAfter(b.Loan, s.Sort, s.Desc)
+		c := ca.CreatedAt.Compare(cb.CreatedAt)
+		if c == 0 {
+			c = cmp.Compare(ca.Number, cb.Number)
+		}
+		if c == 0 {
+			c = strings.Compare(a.ID, b.ID)
+		}
+		if s.Desc {
+			return -c
+		}
+		return c
+	}
+	slices.SortFunc(loans, compare)
+
+	if q.After != nil {
+		after := model.ProductLoan{Loan: model.Loan{ID: q.After.ID, CreatedAt: q.After.CreatedAt, Outstanding: q.After.Number, Amount: q.After.Number}}
+		loans = slices.DeleteFunc(loans, func(loan model.ProductLoan) bool {
+			return compare(loan, after) <= 0
+		})
+	}
+	return loans
+}
+
+// ApplyPayments holds both stores' locks, checking every bill before paying
+// any, so a conflict leaves everything as it was.
+func (r *MemoryLoanRepository) ApplyPayments(_ context.Context, payments []BillPayment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.billings.mu.Lock()
+	defer r.billings.mu.Unlock()
+	return r.applyPayments(payments)
+}
+
+// applyPayments expects the caller to hold r.mu and r.billings.mu.
+func (r *MemoryLoanRepository) applyPayments(payments []BillPayment) error {
+	indexes := make([]int, 0, len(payments))
+	for _, p := range payments {
+		i := slices.IndexFunc(r.billings.bills, func(b model.Billing) bool {
+			return b.ID == p.BillingID && b.LoanID == p.LoanID
+		})
+		if i < 0 || r.billings.bills[i].PaymentDate != nil || slices.Contains(indexes, i) {
+			return ErrConflict
+		}
+		indexes = append(indexes, i)
+	}
+
+	for n, p := range payments {
+		paymentDate := p.PaymentDate
+		r.billings.bills[indexes[n]].PaymentDate = &paymentDate
+
+		loan, ok := r.loans[p.LoanID]
+		if !ok {
+			continue
+		}
+		loan.Outstanding -= p.Amount
+		loan.Status = model.LoanStatusInProgress
+		if loan.Outstanding <= 0 {
+			loan.Status = model.LoanStatusCompleted
+		}
+		r.loans[p.LoanID] = loan
+	}
+	return nil
+}
+
+func (r *MemoryLoanRepository) SummarizeDelinquency(_ context.Context, before time.Time, minOverdue int) (DelinquencySummary, error) {
+	overdue := map[string]int{}
+	for _, b := range r.billings.filter(func(b model.Billing) bool {
+		return b.PaymentDate == nil && b.DueDate.Before(before)
+	}) {
+		overdue[b.LoanID]++
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var summary DelinquencySummary
+	for loanID, count := range overdue {
+		loan, ok := r.loans[loanID]
+		if !ok || count < minOverdue {
+			continue
+		}
+		summary.Loans++
+		summary.Outstanding += loan.Outstanding
+	}
+	return summary, nil
+}
+
+// MemoryBillingRepository keeps bills in insertion order, like the rowid
+// order the SQL backend returns them in.
+type MemoryBillingRepository struct {
+	mu    sync.RWMutex
+	bills []model.Billing
+}
+
+func (r *MemoryBillingRepository) CreateBatch(_ context.Context, bills []model.Billing) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bills = append(r.bills, bills...)
+	return nil
+}
+
+func (r *MemoryBillingRepository) GetByLoanID(_ context.Context, loanID string) ([]model.Billing, error) {
+	return r.filter(func(b model.Billing) bool {
+		return b.LoanID == loanID
+	}), nil
+}
+
+func (r *MemoryBillingRepository) GetUnpaidDueBy(_ context.Context, loanID string, date time.Time) ([]model.Billing, error) {
+	end := EndOfDay(date)
+	bills := r.filter(func(b model.Billing) bool {
+		return b.LoanID == loanID && b.PaymentDate == nil && b.DueDate.Before(end)
+	})
+	slices.SortStableFunc(bills, func(a, b model.Billing) int {
+		return a.DueDate.Compare(b.DueDate)
+	})
+	return bills, nil
+}
+
+func (r *MemoryBillingRepository) filter(keep func(model.Billing) bool) []model.Billing {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var bills []model.Billing
+	for _, b := range r.bills {
+		if keep(b) {
+			bills = append(bills, b)
+		}
+	}
+	return bills
+}
+
+type MemoryProductRepository struct {
+	mu       sync.RWMutex
+	products map[string]model.Product
+}
+
+func (r *MemoryProductRepository) CreateIfMissing(_ context.Context, product *model.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.products[product.Code]; !ok {
+		r.products[product.Code] = *product
+	}
+	return nil
+}
+
+func (r *MemoryProductRepository) GetByCode(_ context.Context, code string) (*model.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	product, ok := r.products[code]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &product, nil
+}
+
+type MemoryCustomerRepository struct {
+	mu        sync.RWMutex
+	customers map[string]model.Customer
+}
+
+func (r *MemoryCustomerRepository) Create(_ context.Context, customer *model.Customer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.customers[customer.ID]; ok {
+		return ErrDuplicate
+	}
+	r.customers[customer.ID] = *customer
+	return nil
+}
+
+func (r *MemoryCustomerRepository) GetByID(_ context.Context, id string) (*model.Customer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	customer, ok := r.customers[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &customer, nil
+}
+
+type MemoryReportRepository struct {
+	loans     *MemoryLoanRepository
+	billings  *MemoryBillingRepository
+	customers *MemoryCustomerRepository
+}
+
+func (r *MemoryReportRepository) Portfolio(_ context.Context, q PortfolioQuery) (PortfolioTotals, error) {
+	var totals PortfolioTotals
+
+	r.loans.mu.RLock()
+	for _, loan := range r.loans.loans {
+		if !loan.CreatedAt.Before(q.From) && loan.CreatedAt.Before(q.To) {
+			totals.LoansDisbursed++
+			totals.Disbursed += loan.Amount
+		}
+	}
+	r.loans.mu.RUnlock()
+
+	type balance struct {
+		outstanding float64
+		overdue     int
+		oldestDue   time.Time
+	}
+	balances := map[string]*balance{}
+	for _, b := range r.billings.filter(func(b model.Billing) bool { return b.CreatedAt.Before(q.To) }) {
+		if b.PaymentDate != nil && b.PaymentDate.Before(q.To) {
+			if !b.PaymentDate.Before(q.From) {
+				totals.Payments++
+				totals.Collected += b.Amount
+			}
+			continue
+		}
+
+		bal, ok := balances[b.LoanID]
+		if !ok {
+			bal = &balance{}
+			balances[b.LoanID] = bal
+		}
+		bal.outstanding += b.Amount
+		if b.DueDate.Before(q.To) {
+			bal.overdue++
+			if bal.oldestDue.IsZero() || b.DueDate.Before(bal.oldestDue) {
+				bal.oldestDue = b.DueDate
+			}
+		}
+	}
+
+	for _, bal := range balances {
+		totals.ActiveLoans++
+		totals.Outstanding += bal.outstanding
+		if bal.overdue >= q.MinOverdue {
+			totals.DelinquentLoans++
+			totals.DelinquentOutstanding += bal.outstanding
+		}
+		if bal.overdue > 0 && bal.oldestDue.Before(q.To.AddDate(0, 0, -30)) {
+			totals.Par30Outstanding += bal.outstanding
+		}
+		if bal.overdue > 0 && bal.oldestDue.Before(q.To.AddDate(0, 0, -90)) {
+			totals.Par90Outstanding += bal.outstanding
+		}
+	}
+
+	return totals, nil
+}
+
+func (r *MemoryReportRepository) ListDue(ctx context.Context, q DueQuery) ([]DueBill, error) {
+	bills := r.billings.filter(func(b model.Billing) bool {
+		return b.PaymentDate == nil && b.DueDate.Before(q.Before) && !b.DueDate.Before(q.From)
+	})
+
+	var due []DueBill
+	for _, b := range bills {
+		loan, err := r.loans.GetByID(ctx, b.LoanID)
+		if err != nil {
+			continue
+		}
+		d := DueBill{Billing: b, CustomerID: loan.CustomerID}
+		if customer, err := r.customers.GetByID(ctx, loan.CustomerID); err == nil {
+			d.CustomerName, d.CustomerPhone, d.CustomerBranch = customer.Name, customer.Phone, customer.Branch
+		}
+		if q.Branch != "" && d.CustomerBranch != q.Branch {
+			continue
+		}
+		due = append(due, d)
+	}
+
+	slices.SortStableFunc(due, func(a, b DueBill) int {
+		if c := strings.Compare(a.CustomerID, b.CustomerID); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.LoanID, b.LoanID); c != 0 {
+			return c
+		}
+		return a.DueDate.Compare(b.DueDate)
+	})
+	return due, nil
+}
+
+type MemoryPaymentImportRepository struct {
+	mu      sync.Mutex
+	imports map[string]model.PaymentImport
+	loans   *MemoryLoanRepository
+}
+
+func (r *MemoryPaymentImportRepository) Applied(_ context.Context, reference string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec, ok := r.imports[reference]
+	return ok && rec.Status != model.PaymentImportPending, nil
+}
+
+// Apply holds its own lock and the loan store's while paying, so the
+// reference is stored only together with its payments.
+func (r *MemoryPaymentImportRepository) Apply(_ context.Context, rec *model.PaymentImport, payments []BillPayment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.imports[rec.Reference]; ok && existing.Status != model.PaymentImportPending {
+		return ErrDuplicate
+	}
+
+	r.loans.mu.Lock()
+	defer r.loans.mu.Unlock()
+	r.loans.billings.mu.Lock()
+	defer r.loans.billings.mu.Unlock()
+	if err := r.loans.applyPayments(payments); err != nil {
+		return err
+	}
+	r.imports[rec.Reference] = *rec
+	return nil
+}
+
+type MemoryGroupRepository struct {
+	mu      sync.RWMutex
+	groups  map[string]model.LoanGroup
+	members map[string]model.LoanGroupMember
+	loans   *MemoryLoanRepository
+}
+
+func (r *MemoryGroupRepository) Create(_ context.Context, group *model.LoanGroup, members []model.LoanGroupMember) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.anyGrouped(members) {
+		return ErrDuplicate
+	}
+	r.groups[group.ID] = *group
+	for _, m := range members {
+		r.members[m.LoanID] = m
+	}
+	return nil
+}
+
+func (r *MemoryGroupRepository) GetByID(_ context.Context, id string) (*model.LoanGroup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	group, ok := r.groups[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &group, nil
+}
+
+func (r *MemoryGroupRepository) AddMembers(_ context.Context, members []model.LoanGroupMember) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.anyGrouped(members) {
+		return ErrDuplicate
+	}
+	for _, m := range members {
+		r.members[m.LoanID] = m
+	}
+	return nil
+}
+
+// anyGrouped reports whether one of members' loans is in a group already.
+// The caller holds r.mu.
+func (r *MemoryGroupRepository) anyGrouped(members []model.LoanGroupMember) bool {
+	return slices.ContainsFunc(members, func(m model.LoanGroupMember) bool {
+		_, ok := r.members[m.LoanID]
+		return ok
+	})
+}
+
+func (r *MemoryGroupRepository) GroupOf(_ context.Context, loanID string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.members[loanID]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return m.GroupID, nil
+}
+
+func (r *MemoryGroupRepository) ListLoans(ctx context.Context, groupID string) ([]model.Loan, error) {
+	r.mu.RLock()
+	var members []model.LoanGroupMember
+	for _, m := range r.members {
+		if m.GroupID == groupID {
+			members = append(members, m)
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(members, func(a, b model.LoanGroupMember) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.LoanID, b.LoanID)
+	})
+	loans := make([]model.Loan, 0, len(members))
+	for _, m := range members {
+		loan, err := r.loans.GetByID(ctx, m.LoanID)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, *loan)
+	}
+	return loans, nil
+}
@@ -0,0 +1,181 @@
+// Package repository hides how loans, bills and products are stored so the
+// usecases can run on any backend.
+package repository
+
+import (
+	"billing/internal/model"
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when the requested record does not exist.
+var ErrNotFound = errors.New("record not found")
+
+// ErrDuplicate is returned when a record with the same key already exists.
+var ErrDuplicate = errors.New("record already exists")
+
+// ErrConflict is returned when a record changed after it was read, e.g. a bill
+// that another request paid first.
+var ErrConflict = errors.New("record changed concurrently")
+
+type LoanRepository interface {
+	Create(ctx context.Context, loan *model.ProductLoan) error
+	// CreateBatch stores loans and their bills in one transaction, so either
+	// all of them are stored or none is.
+	CreateBatch(ctx context.Context, loans []model.ProductLoan, bills []model.Billing) error
+	GetByID(ctx context.Context, id string) (*model.Loan, error)
+	// ProductCode returns the code of the product the loan was issued under.
+	ProductCode(ctx context.Context, loanID string) (string, error)
+	// ListByCustomerID returns the customer's loans, oldest first.
+	ListByCustomerID(ctx context.Context, customerID string) ([]model.Loan, error)
+	// Search returns at most q.Search.Limit loans matching q, in sort order.
+	Search(ctx context.Context, q LoanQuery) ([]model.ProductLoan, error)
+	// Each calls fn for every loan matching q in sort order, ignoring
+	// q.Search.Limit, reading loans one at a time. It stops at fn's first
+	// error and returns it.
+	Each(ctx context.Context, q LoanQuery, fn func(model.ProductLoan) error) error
+	// ApplyPayments marks each bill paid and subtracts its amount from its
+	// loan's outstanding, completing loans paid off, in one transaction. If
+	// any bill is no longer unpaid it returns ErrConflict and applies none.
+	ApplyPayments(ctx context.Context, payments []BillPayment) error
+	// SummarizeDelinquency counts the loans with at least minOverdue unpaid
+	// bills due before the given time, and totals their outstanding.
+	SummarizeDelinquency(ctx context.Context, before time.Time, minOverdue int) (DelinquencySummary, error)
+}
+
+// BillPayment pays one bill of a loan in full.
+type BillPayment struct {
+	LoanID      string
+	BillingID   string
+	Amount      float64
+	PaymentDate time.Time
+}
+
+type DelinquencySummary struct {
+	Loans       int64
+	Outstanding float64
+}
+
+type BillingRepository interface {
+	CreateBatch(ctx context.Context, bills []model.Billing) error
+	GetByLoanID(ctx context.Context, loanID string) ([]model.Billing, error)
+	// GetUnpaidDueBy returns the loan's unpaid bills whose due date falls on
+	// or before the day of date, oldest first.
+	GetUnpaidDueBy(ctx context.Context, loanID string, date time.Time) ([]model.Billing, error)
+}
+
+type ProductRepository interface {
+	// CreateIfMissing stores product unless one with the same code exists.
+	CreateIfMissing(ctx context.Context, product *model.Product) error
+	GetByCode(ctx context.Context, code string) (*model.Product, error)
+}
+
+type CustomerRepository interface {
+	// Create fails with ErrDuplicate when the ID is taken.
+	Create(ctx context.Context, customer *model.Customer) error
+	GetByID(ctx context.Context, id string) (*model.Customer, error)
+}
+
+// ReportRepository aggregates the loan book in the backend rather than
+// loading it.
+type ReportRepository interface {
+	Portfolio(ctx context.Context, q PortfolioQuery) (PortfolioTotals, error)
+	// ListDue returns the unpaid bills due before q.Before, and on or after
+	// q.From unless it is zero, ordered by customer, loan and due date.
+	ListDue(ctx context.Context, q DueQuery) ([]DueBill, error)
+}
+
+// PortfolioQuery covers created_at and payment_date in [From, To). Balances
+// are taken at To: a bill counts as unpaid unless it was paid before To.
+type PortfolioQuery struct {
+	From, To   time.Time
+	MinOverdue int
+}
+
+type PortfolioTotals struct {
+	LoansDisbursed int64
+	Disbursed      float64
+	Payments       int64
+	Collected      float64
+
+	ActiveLoans           int64
+	Outstanding           float64
+	DelinquentLoans       int64
+	DelinquentOutstanding float64
+	// Par30Outstanding and Par90Outstanding total the outstanding of loans
+	// with a bill unpaid more than 30 or 90 days after it fell due.
+	Par30Outstanding float64
+	Par90Outstanding float64
+}
+
+type DueQuery struct {
+	From, Before time.Time
+	Branch       string
+}
+
+// DueBill is a bill with the customer it is collected from. The customer
+// fields are empty when the loan's customer has no record.
+type DueBill struct {
+	model.Billing
+	CustomerID     string
+	CustomerName   string
+	CustomerPhone  string
+	CustomerBranch string
+}
+
+// PaymentImportRepository remembers the bank references of imported payments.
+type PaymentImportRepository interface {
+	// Applied reports whether the reference's payment was posted.
+	Applied(ctx context.Context, reference string) (bool, error)
+	// Apply stores rec as applied and posts payments as
+	// LoanRepository.ApplyPayments does, in one transaction. It returns
+	// ErrDuplicate if the reference was applied already and ErrConflict if a
+	// bill is no longer unpaid; either way nothing is stored. A pending record
+	// left by an import that stopped before posting is replaced.
+	Apply(ctx context.Context, rec *model.PaymentImport, payments []BillPayment) error
+}
+
+// GroupRepository stores loan groups and which loans belong to them.
+type GroupRepository interface {
+	// Create stores group together with its first members. Create and
+	// AddMembers store nothing and return ErrDuplicate if one of the loans
+	// is in a group already.
+	Create(ctx context.Context, group *model.LoanGroup, members []model.LoanGroupMember) error
+	GetByID(ctx context.Context, id string) (*model.LoanGroup, error)
+	AddMembers(ctx context.Context, members []model.LoanGroupMember) error
+	// GroupOf returns the ID of the loan's group, or ErrNotFound.
+	GroupOf(ctx context.Context, loanID string) (string, error)
+	// ListLoans returns the group's loans in the order they joined, by the
+	// members' CreatedAt.
+	ListLoans(ctx context.Context, groupID string) ([]model.Loan, error)
+}
+
+// HealthChecker reports whether the backend can serve requests.
+type HealthChecker interface {
+	Ready(ctx context.Context) error
+}
+
+// Repositories groups one backend's implementations.
+type Repositories struct {
+	Loans     LoanRepository
+	Billings  BillingRepository
+	Products  ProductRepository
+	Customers CustomerRepository
+	Reports   ReportRepository
+	Imports   PaymentImportRepository
+	Groups    GroupRepository
+	Health    HealthChecker
+}
+
+// StartOfDay returns midnight at the start of t's day, in t's location.
+func StartOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// EndOfDay returns midnight at the start of the day after t. Date-only rules
+// are written as half-open ranges against it (due_date < EndOfDay(t)) rather
+// than with a SQL DATE() function, whose behaviour differs between drivers.
+func EndOfDay(t time.Time) time.Time {
+	return StartOfDay(t).AddDate(0, 0, 1)
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"billing/internal/model"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a cursor that was not issued for the same
+// sort column and direction.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// LoanQuery selects one page of loans. Loans are ordered by Search.Sort and
+// then by ID, so pages stay stable when sort keys tie.
+type LoanQuery struct {
+	Search model.LoanSearch
+	// After is the last loan of the previous page, nil for the first page.
+	After *LoanCursor
+	// DelinquentBefore and MinOverdue define delinquency for
+	// Search.Delinquent, as in SummarizeDelinquency.
+	DelinquentBefore time.Time
+	MinOverdue       int
+}
+
+// LoanCursor is the sort key of the last loan on a page.
+type LoanCursor struct {
+	Sort      string    `json:"s"`
+	Desc      bool      `json:"d,omitempty"`
+	CreatedAt time.Time `json:"t,omitempty"`
+	Number    float64   `json:"n,omitempty"`
+	ID        string    `json:"id"`
+}
+
+// CursorAfter returns the cursor that continues a search sorted by sort, in
+// descending order when desc is set, after loan.
+func CursorAfter(loan model.Loan, sort string, desc bool) LoanCursor {
+	c := LoanCursor{Sort: sort, Desc: desc, ID: loan.ID}
+	switch sort {
+	case model.LoanSortOutstanding:
+		c.Number = loan.Outstanding
+	case model.LoanSortAmount:
+		c.Number = loan.Amount
+	default:
+		c.CreatedAt = loan.CreatedAt
+	}
+	return c
+}
+
+// Encode returns the opaque form handed to clients as next_cursor.
+func (c LoanCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor from Encode and checks it belongs to sort in
+// the same direction: a cursor from an ascending search would skip the wrong
+// side of the list in a descending one.
+func DecodeCursor(s, sort string, desc bool) (*LoanCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c LoanCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" || c.Sort != sort || c.Desc != desc {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// key returns the value of the sort column the cursor points at.
+func (c LoanCursor) key() any {
+	if c.Sort == model.LoanSortCreatedAt {
+		return c.CreatedAt
+	}
+	return c.Number
+}
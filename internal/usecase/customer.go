@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"billing/internal/apperror"
+	"billing/internal/auth"
+	"billing/internal/model"
+	"billing/internal/repository"
+	"billing/internal/tracing"
+	"billing/internal/util"
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrCustomerNotFound is returned when no customer has the requested ID.
+func ErrCustomerNotFound(customerID string) *apperror.Error {
+	return apperror.NotFound("CUSTOMER_NOT_FOUND", "customer_id %s not found", customerID)
+}
+
+// ErrCustomerExists is returned when registering an ID that is already taken.
+func ErrCustomerExists(customerID string) *apperror.Error {
+	return apperror.Conflict("CUSTOMER_EXISTS", "customer_id %s already exists", customerID)
+}
+
+// CreateCustomer registers a borrower so that loans can be issued to them.
+func (u *LoanUsecase) CreateCustomer(ctx context.Context, req model.CreateCustomerRequest) (*model.Customer, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "LoanUsecase.CreateCustomer")
+	defer span.End()
+
+	timeNow := util.GetCurrentTime()
+	customer := model.Customer{
+		ID:        req.ID,
+		Name:      strings.TrimSpace(req.Name),
+		Phone:     strings.TrimSpace(req.Phone),
+		KYCStatus: req.KYCStatus,
+		Branch:    req.Branch,
+		CreatedAt: timeNow,
+		UpdatedAt: timeNow,
+	}
+	if customer.KYCStatus == "" {
+		customer.KYCStatus = model.KYCStatusPending
+	}
+	if err := u.Customers.Create(ctx, &customer); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrCustomerExists(customer.ID).Wrap(err)
+		}
+		u.logger().ErrorContext(ctx, "create customer failed", "customer_id", customer.ID, "error", err)
+		return nil, err
+	}
+	u.logger().InfoContext(ctx, "customer created", "customer_id", customer.ID, "kyc_status", customer.KYCStatus)
+
+	return &customer, nil
+}
+
+// ListCustomerLoans returns the customer with every one of their loans and
+// each loan's current delinquency.
+func (u *LoanUsecase) ListCustomerLoans(ctx context.Context, customerID string) (*model.CustomerLoans, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "LoanUsecase.ListCustomerLoans")
+	defer span.End()
+
+	if principal, ok := auth.FromContext(ctx); ok {
+		if scope, scoped := principal.CustomerScope(); scoped && scope != customerID {
+			return nil, ErrCustomerNotFound(customerID)
+		}
+	}
+
+	customer, err := u.Customers.GetByID(ctx, customerID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrCustomerNotFound(customerID).Wrap(err)
+		}
+		u.logger().ErrorContext(ctx, "get customer failed", "customer_id", customerID, "error", err)
+		return nil, err
+	}
+
+	loans, err := u.Loans.ListByCustomerID(ctx, customerID)
+	if err != nil {
+		u.logger().ErrorContext(ctx, "list loans failed", "customer_id", customerID, "error", err)
+		return nil, err
+	}
+
+	resp := model.CustomerLoans{
+		Customer: *customer,
+		Loans:    make([]model.CustomerLoan, 0, len(loans)),
+	}
+	timeNow := util.GetCurrentTime()
+	for _, loan := range loans {
+		status, err := u.billStatus(ctx, loan.ID, timeNow)
+		if err != nil {
+			return nil, err
+		}
+
+		item := model.CustomerLoan{Loan: loan, IsDelinquent: status.IsDelinquent}
+		if status.IsDelinquent {
+			item.DelinquentAt = &status.DelinquentAt
+		}
+		resp.Loans = append(resp.Loans, item)
+	}
+
+	return &resp, nil
+}
@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"billing/internal/apperror"
+	"billing/internal/model"
+	"billing/internal/repository"
+	"billing/internal/validation"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrCustomerNotEligible is returned when a loan breaks one or more
+// eligibility rules; its fields carry one reason per broken rule.
+var ErrCustomerNotEligible = apperror.BusinessRuleViolated("CUSTOMER_NOT_ELIGIBLE", "customer is not eligible for a new loan")
+
+// Reasons reported in the fields of ErrCustomerNotEligible.
+const (
+	ReasonKYCNotVerified  = "KYC_NOT_VERIFIED"
+	ReasonActiveLoanLimit = "ACTIVE_LOAN_LIMIT"
+	ReasonExposureLimit   = "EXPOSURE_LIMIT"
+	ReasonDelinquentLoan  = "DELINQUENT_LOAN"
+)
+
+// EligibilityRules limit a customer's concurrent borrowing. Zero limits are
+// not enforced.
+type EligibilityRules struct {
+	// MaxActiveLoans caps the loans a customer may have that are not completed.
+	MaxActiveLoans int
+	// MaxExposure caps the customer's total outstanding, new loan included.
+	MaxExposure float64
+	// BlockWhenDelinquent refuses new loans while any active loan is
+	// delinquent, as GetBillStatus reports it.
+	BlockWhenDelinquent bool
+}
+
+// checkEligibility evaluates every rule for a new loan and reports all those it
+// breaks at once. Loans for customers without a record are rejected when
+// RequireRegisteredCustomer is set, as it is by default; otherwise only the
+// loan-based rules apply to them. Pending loans
+// are accepted but not stored yet, such as earlier rows of the same batch.
+func (u *LoanUsecase) checkEligibility(ctx context.Context, req model.Loan, pending []model.Loan, now time.Time) error {
+	var reasons validation.Errors
+
+	customer, err := u.Customers.GetByID(ctx, req.CustomerID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		if u.RequireRegisteredCustomer {
+			var errs validation.Errors
+			errs.Add("customer_id", validation.CodeUnknownReference, "unknown customer %q", req.CustomerID)
+			return apperror.Validation(errs)
+		}
+	case err != nil:
+		u.logger().ErrorContext(ctx, "get customer failed", "customer_id", req.CustomerID, "error", err)
+		return err
+	case customer.KYCStatus != model.KYCStatusVerified:
+		reasons.Add("customer_id", ReasonKYCNotVerified, "customer KYC status is %s", customer.KYCStatus)
+	}
+
+	loans, err := u.Loans.ListByCustomerID(ctx, req.CustomerID)
+	if err != nil {
+		u.logger().ErrorContext(ctx, "list loans failed", "customer_id", req.CustomerID, "error", err)
+		return err
+	}
+
+	rules := u.Eligibility
+	active := 0
+	exposure := req.TotalAmount
+	for _, loan := range loans {
+		if loan.Status == model.LoanStatusCompleted {
+			continue
+		}
+		active++
+		exposure += loan.Outstanding
+
+		if !rules.BlockWhenDelinquent {
+			continue
+		}
+		status, err := u.billStatus(ctx, loan.ID, now)
+		if err != nil {
+			return err
+		}
+		if status.IsDelinquent {
+			reasons.Add("customer_id", ReasonDelinquentLoan, "loan %s is delinquent since %s", loan.ID, status.DelinquentAt.Format(time.DateOnly))
+		}
+	}
+
+	for _, loan := range pending {
+		if loan.CustomerID == req.CustomerID {
+			active++
+			exposure += loan.Outstanding
+		}
+	}
+
+	if rules.MaxActiveLoans > 0 && active >= rules.MaxActiveLoans {
+		reasons.Add("customer_id", ReasonActiveLoanLimit, "customer has %d active loans, the limit is %d", active, rules.MaxActiveLoans)
+	}
+	if rules.MaxExposure > 0 && exposure > rules.MaxExposure {
+		reasons.Add("amount", ReasonExposureLimit, "total outstanding would be %.0f, the limit is %.0f", exposure, rules.MaxExposure)
+	}
+
+	if len(reasons) > 0 {
+		return ErrCustomerNotEligible.WithFields(reasons)
+	}
+	return nil
+}
+
+// customerLocks serialises loan creation per customer, so one request's
+// eligibility check and insert cannot interleave with another's for the same
+// customer and both pass limits that only one of them fits. It covers the
+// requests served by this process.
+type customerLocks struct {
+	mu    sync.Mutex
+	locks map[string]*customerLock
+}
+
+type customerLock struct {
+	sync.Mutex
+	// waiters counts the holder and the callers waiting for the lock; the
+	// lock is dropped from the map when it reaches zero.
+	waiters int
+}
+
+// lock takes the lock of every customer in customerIDs, in sorted order so two
+// batches sharing customers cannot deadlock, and returns the func releasing
+// them.
+func (l *customerLocks) lock(customerIDs ...string) (unlock func()) {
+	ids := slices.Clone(customerIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	held := make([]*customerLock, 0, len(ids))
+	for _, id := range ids {
+		l.mu.Lock()
+		if l.locks == nil {
+			l.locks = make(map[string]*customerLock)
+		}
+		c, ok := l.locks[id]
+		if !ok {
+			c = &customerLock{}
+			l.locks[id] = c
+		}
+		c.waiters++
+		l.mu.Unlock()
+
+		c.Lock()
+		held = append(held, c)
+	}
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for i, c := range held {
+			c.Unlock()
+			if c.waiters--; c.waiters == 0 {
+				delete(l.locks, ids[i])
+			}
+		}
+	}
+}
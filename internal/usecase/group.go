@@ -0,0 +1,304 @@
+package usecase
+
+import (
+	"billing/internal/apperror"
+	"billing/internal/auth"
+	"billing/internal/metrics"
+	"billing/internal/model"
+	"billing/internal/repository"
+	"billing/internal/tracing"
+	"billing/internal/util"
+	"billing/internal/validation"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrGroupNotFound is returned when no group has the requested ID.
+func ErrGroupNotFound(groupID string) *apperror.Error {
+	return apperror.NotFound("GROUP_NOT_FOUND", "group_id %s not found", groupID)
+}
+
+// ErrLoanAlreadyGrouped is returned when a loan to add is in a group already;
+// its fields name each such loan.
+var ErrLoanAlreadyGrouped = apperror.Conflict("LOAN_ALREADY_GROUPED", "loan already belongs to a group")
+
+// ErrGroupAmountMismatch is returned when a group payment does not add up to
+// whole installments of the bills due.
+var ErrGroupAmountMismatch = apperror.ValidationFailed("GROUP_AMOUNT_MISMATCH", "payment_amount cannot be split into whole installments")
+
+// ErrGroupPaymentForbidden keeps borrowers from paying other members' loans.
+var ErrGroupPaymentForbidden = apperror.Forbidden("GROUP_PAYMENT_FORBIDDEN", "group payments are posted by staff")
+
+// allocationTolerance absorbs float rounding when adding up installments.
+const allocationTolerance = 0.01
+
+// CreateGroup stores a new group with the given loans and returns its view.
+func (u *LoanUsecase) CreateGroup(ctx context.Context, req model.CreateGroupRequest) (*model.LoanGroupView, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "LoanUsecase.CreateGroup")
+	defer span.End()
+
+	if err := u.checkGroupLoans(ctx, req.LoanIDs); err != nil {
+		return nil, err
+	}
+
+	timeNow := util.GetCurrentTime()
+	group := model.LoanGroup{
+		ID:        uuid.New().String(),
+		Name:      req.Name,
+		Branch:    req.Branch,
+		CreatedAt: timeNow,
+		UpdatedAt: timeNow,
+	}
+	if err := u.Groups.Create(ctx, &group, groupMembers(group.ID, req.LoanIDs, timeNow)); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, u.groupedSince(ctx, req.LoanIDs)
+		}
+		u.logger().ErrorContext(ctx, "create group failed", "error", err)
+		return nil, err
+	}
+	u.logger().InfoContext(ctx, "group created", "group_id", group.ID, "loans", len(req.LoanIDs))
+
+	return u.GetGroup(ctx, group.ID)
+}
+
+// AddGroupLoans adds loans to an existing group and returns its view.
+func (u *LoanUsecase) AddGroupLoans(ctx context.Context, groupID string, loanIDs []string) (*model.LoanGroupView, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "LoanUsecase.AddGroupLoans")
+	defer span.End()
+
+	if _, err := u.getGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+	if err := u.checkGroupLoans(ctx, loanIDs); err != nil {
+		return nil, err
+	}
+
+	if err := u.Groups.AddMembers(ctx, groupMembers(groupID, loanIDs, util.GetCurrentTime())); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, u.groupedSince(ctx, loanIDs)
+		}
+		u.logger().ErrorContext(ctx, "add group loans failed", "group_id", groupID, "error", err)
+		return nil, err
+	}
+	u.logger().InfoContext(ctx, "group loans added", "group_id", groupID, "loans", len(loanIDs))
+
+	return u.GetGroup(ctx, groupID)
+}
+
+// GetGroup returns the group's loans with their combined outstanding, the
+// amount due today and delinquency. Borrowers see only groups they belong to,
+// and in them only their own loans.
+func (u *LoanUsecase) GetGroup(ctx context.Context, groupID string) (*model.LoanGroupView, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "LoanUsecase.GetGroup")
+	defer span.End()
+
+	group, err := u.getGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	loans, err := u.Groups.ListLoans(ctx, groupID)
+	if err != nil {
+		u.logger().ErrorContext(ctx, "list group loans failed", "group_id", groupID, "error", err)
+		return nil, err
+	}
+
+	if principal, ok := auth.FromContext(ctx); ok {
+		if customerID, scoped := principal.CustomerScope(); scoped {
+			loans = slices.DeleteFunc(loans, func(loan model.Loan) bool {
+				return loan.CustomerID != customerID
+			})
+			if len(loans) == 0 {
+				return nil, ErrGroupNotFound(groupID)
+			}
+		}
+	}
+
+	view := model.LoanGroupView{
+		Group: *group,
+		Loans: make([]model.GroupLoan, 0, len(loans)),
+	}
+	timeNow := util.GetCurrentTime()
+	for _, loan := range loans {
+		bills, err := u.Billings.GetUnpaidDueBy(ctx, loan.ID, timeNow)
+		if err != nil {
+			u.logger().ErrorContext(ctx, "get unpaid bills failed", "loan_id", loan.ID, "error", err)
+			return nil, err
+		}
+
+		item := model.GroupLoan{Loan: loan}
+		if status := u.statusOf(loan.ID, bills); status.IsDelinquent {
+			item.IsDelinquent = true
+			item.DelinquentAt = &status.DelinquentAt
+			view.DelinquentLoans++
+		}
+		for _, b := range bills {
+			item.DueAmount += b.Amount
+		}
+
+		view.TotalOutstanding += loan.Outstanding
+		view.DueAmount += item.DueAmount
+		view.Loans = append(view.Loans, item)
+	}
+	view.IsDelinquent = view.DelinquentLoans > 0
+
+	return &view, nil
+}
+
+// MakeGroupPayment splits the lump sum collected at a group's meeting over
+// the bills due by payment_date, oldest due date first and, between bills due
+// the same day, in the order loans joined the group. Bills are paid in full
+// and in that order, without skipping one the rest of the sum cannot cover,
+// so a sum that does not come to whole installments is refused. Every bill is
+// paid in one transaction: either the whole sum is posted or nothing is.
+func (u *LoanUsecase) MakeGroupPayment(ctx context.Context, groupID string, req model.GroupPaymentRequest) (*model.GroupPayment, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "LoanUsecase.MakeGroupPayment")
+	defer span.End()
+
+	if principal, ok := auth.FromContext(ctx); ok {
+		if _, scoped := principal.CustomerScope(); scoped {
+			return nil, ErrGroupPaymentForbidden
+		}
+	}
+
+	if _, err := u.getGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+	loans, err := u.Groups.ListLoans(ctx, groupID)
+	if err != nil {
+		u.logger().ErrorContext(ctx, "list group loans failed", "group_id", groupID, "error", err)
+		return nil, err
+	}
+
+	var due []model.GroupAllocation
+	for _, loan := range loans {
+		bills, err := u.Billings.GetUnpaidDueBy(ctx, loan.ID, req.PaymentDate)
+		if err != nil {
+			u.logger().ErrorContext(ctx, "get unpaid bills failed", "loan_id", loan.ID, "error", err)
+			return nil, err
+		}
+		for _, b := range bills {
+			due = append(due, model.GroupAllocation{
+				LoanID:     loan.ID,
+				CustomerID: loan.CustomerID,
+				BillingID:  b.ID,
+				Sequence:   b.Sequence,
+				DueDate:    b.DueDate,
+				Amount:     b.Amount,
+			})
+		}
+	}
+	if len(due) == 0 {
+		return nil, ErrNoPendingBill
+	}
+	slices.SortStableFunc(due, func(a, b model.GroupAllocation) int {
+		return a.DueDate.Compare(b.DueDate)
+	})
+
+	resp := model.GroupPayment{GroupID: groupID, Amount: req.PaymentAmount, Date: req.PaymentDate}
+	remaining, totalDue := req.PaymentAmount, 0.0
+	for _, a := range due {
+		totalDue += a.Amount
+	}
+	for _, a := range due {
+		if a.Amount > remaining+allocationTolerance {
+			break
+		}
+		resp.Allocations = append(resp.Allocations, a)
+		remaining -= a.Amount
+	}
+	if remaining > allocationTolerance {
+		var errs validation.Errors
+		errs.Add("payment_amount", ErrGroupAmountMismatch.Code, "%.2f is left over after paying whole installments, %.2f is due in total", remaining, totalDue)
+		return nil, ErrGroupAmountMismatch.WithFields(errs)
+	}
+
+	payments := make([]repository.BillPayment, 0, len(resp.Allocations))
+	for _, a := range resp.Allocations {
+		payments = append(payments, repository.BillPayment{LoanID: a.LoanID, BillingID: a.BillingID, Amount: a.Amount, PaymentDate: req.PaymentDate})
+	}
+	if err := u.Loans.ApplyPayments(ctx, payments); err != nil {
+		u.Metrics.ObservePayment(metrics.OutcomeError, req.PaymentAmount)
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrBillAlreadyPaid.Wrap(err)
+		}
+		u.logger().ErrorContext(ctx, "group payment failed", "group_id", groupID, "error", err)
+		return nil, err
+	}
+	for _, a := range resp.Allocations {
+		u.Metrics.ObservePayment(metrics.OutcomeSuccess, a.Amount)
+	}
+
+	span.SetAttributes(attribute.Int("billing.group.bills_paid", len(resp.Allocations)))
+	u.logger().InfoContext(ctx, "group payment applied", "group_id", groupID, "amount", req.PaymentAmount, "bills", len(resp.Allocations))
+	return &resp, nil
+}
+
+func (u *LoanUsecase) getGroup(ctx context.Context, groupID string) (*model.LoanGroup, error) {
+	group, err := u.Groups.GetByID(ctx, groupID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrGroupNotFound(groupID).Wrap(err)
+		}
+		u.logger().ErrorContext(ctx, "get group failed", "group_id", groupID, "error", err)
+		return nil, err
+	}
+	return group, nil
+}
+
+// checkGroupLoans makes sure every loan exists and is in no group yet.
+func (u *LoanUsecase) checkGroupLoans(ctx context.Context, loanIDs []string) error {
+	var unknown, grouped validation.Errors
+	for _, loanID := range loanIDs {
+		if _, err := u.Loans.GetByID(ctx, loanID); err != nil {
+			if !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+			unknown.Add("loan_ids", validation.CodeUnknownReference, "unknown loan %q", loanID)
+			continue
+		}
+
+		groupID, err := u.Groups.GroupOf(ctx, loanID)
+		switch {
+		case err == nil:
+			grouped.Add("loan_ids", ErrLoanAlreadyGrouped.Code, "loan %s is in group %s", loanID, groupID)
+		case !errors.Is(err, repository.ErrNotFound):
+			return err
+		}
+	}
+
+	if len(unknown) > 0 {
+		return apperror.Validation(unknown)
+	}
+	if len(grouped) > 0 {
+		return ErrLoanAlreadyGrouped.WithFields(grouped)
+	}
+	return nil
+}
+
+// groupedSince explains a lost race to group loanIDs: another request grouped
+// one of them after checkGroupLoans passed. It names the loans when it can.
+func (u *LoanUsecase) groupedSince(ctx context.Context, loanIDs []string) error {
+	if err := u.checkGroupLoans(ctx, loanIDs); err != nil {
+		return err
+	}
+	return ErrLoanAlreadyGrouped
+}
+
+// groupMembers lists loanIDs as joining groupID at joined, a microsecond
+// apart so they keep the given order at the backends' time precision.
+func groupMembers(groupID string, loanIDs []string, joined time.Time) []model.LoanGroupMember {
+	members := make([]model.LoanGroupMember, 0, len(loanIDs))
+	for i, loanID := range loanIDs {
+		members = append(members, model.LoanGroupMember{
+			LoanID:    loanID,
+			GroupID:   groupID,
+			CreatedAt: joined.Add(time.Duration(i) * time.Microsecond),
+		})
+	}
+	return members
+}
@@ -1,51 +1,147 @@
 package usecase
 
 import (
+	"billing/internal/apperror"
+	"billing/internal/auth"
+	"billing/internal/logging"
+	"billing/internal/metrics"
 	"billing/internal/model"
+	"billing/internal/repository"
+	"billing/internal/tracing"
 	"billing/internal/util"
+	"context"
 	"errors"
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
-	"gorm.io/gorm"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const defaultDelinquencyThreshold = 2
+
 type LoanUsecase struct {
-	DB *gorm.DB
+	Loans     repository.LoanRepository
+	Billings  repository.BillingRepository
+	Products  repository.ProductRepository
+	Customers repository.CustomerRepository
+	Groups    repository.GroupRepository
+
+	// DefaultProductCode is used for loans created without a product_code;
+	// when empty such loans are rejected.
+	DefaultProductCode string
+	// DelinquencyThreshold is how many overdue bills make a loan delinquent.
+	DelinquencyThreshold int
+	// RequireRegisteredCustomer rejects loans for customers with no record.
+	RequireRegisteredCustomer bool
+	// Eligibility limits how much a customer may borrow at once.
+	Eligibility EligibilityRules
+	// customerLocks holds a customer's loan creation from the eligibility
+	// check until the loan is stored.
+	customerLocks customerLocks
+
+	Metrics *metrics.Metrics
+	Logger  *slog.Logger
 }
 
-var ErrNoPendingBill = errors.New("no pending bills for spcified payment_date")
-var ErrInsufficientAmount = errors.New("insuffiecient payment amount")
+var ErrNoPendingBill = apperror.BusinessRuleViolated("NO_PENDING_BILL", "no pending bills for spcified payment_date")
+var ErrInsufficientAmount = apperror.ValidationFailed("INSUFFICIENT_AMOUNT", "insuffiecient payment amount")
+
+// ErrBillAlreadyPaid is returned when a concurrent request paid the bill
+// between reading and paying it.
+var ErrBillAlreadyPaid = apperror.Conflict("BILL_ALREADY_PAID", "bill was paid by a concurrent request")
+
+// ErrLoanNotFound is returned when no loan has the requested ID.
+func ErrLoanNotFound(loanID string) *apperror.Error {
+	return apperror.NotFound("LOAN_NOT_FOUND", "loan_id %s not found", loanID)
+}
 
-func (u *LoanUsecase) isLoanIDExist(loanID string) (*model.Loan, error) {
-	var loan model.Loan
-	if err := u.DB.Where("id = ?", loanID).First(&loan).Error; err != nil {
-		return nil, err 
+// isLoanIDExist loads a loan the caller may access. A borrower asking for
+// another customer's loan gets the same error as for a missing one, so loan
+// IDs cannot be probed.
+func (u *LoanUsecase) isLoanIDExist(ctx context.Context, loanID string) (*model.Loan, error) {
+	loan, err := u.Loans.GetByID(ctx, loanID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrLoanNotFound(loanID).Wrap(err)
+		}
+		return nil, err
 	}
-	return &loan, nil
+
+	if principal, ok := auth.FromContext(ctx); ok {
+		if customerID, scoped := principal.CustomerScope(); scoped && loan.CustomerID != customerID {
+			return nil, ErrLoanNotFound(loanID)
+		}
+	}
+	return loan, nil
 }
 
-func (u *LoanUsecase) CreateBills(req model.Loan) (*model.LoanWithBills, error) {
-	var err error
-	var resp model.LoanWithBills
-	timeNow := util.GetCurrentTime()
+func (u *LoanUsecase) CreateBills(ctx context.Context, req model.ProductLoan) (*model.LoanWithBills, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "LoanUsecase.CreateBills")
+	defer span.End()
+
+	defer u.customerLocks.lock(req.CustomerID)()
+
+	resp, err := u.newLoan(ctx, req, nil, util.GetCurrentTime())
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(tracing.LoanID(resp.Loan.ID))
+
+	// The loan and its bills are stored together, so a failure leaves no
+	// loan without a schedule.
+	if err = u.Loans.CreateBatch(ctx, []model.ProductLoan{resp.productLoan()}, resp.Bills); err != nil {
+		u.logger().ErrorContext(ctx, "create loan failed", "loan_id", resp.Loan.ID, "customer_id", req.CustomerID, "error", err)
+		return nil, err
+	}
+
+	u.Metrics.ObserveLoanCreated()
+	u.logger().InfoContext(ctx, "loan created", "loan_id", resp.Loan.ID, "customer_id", resp.Loan.CustomerID, "product_code", resp.ProductCode, "amount", resp.Loan.Amount)
+
+	return &resp.LoanWithBills, nil
+}
+
+// issuedLoan is a new loan with its schedule and the product it was issued
+// under, ready to store.
+type issuedLoan struct {
+	model.LoanWithBills
+	ProductCode string
+}
+
+func (l *issuedLoan) productLoan() model.ProductLoan {
+	return model.ProductLoan{Loan: l.Loan, ProductCode: l.ProductCode}
+}
+
+// newLoan validates req against its product and the customer's eligibility,
+// counting pending loans not stored yet, and returns the loan with its
+// schedule, ready to store.
+func (u *LoanUsecase) newLoan(ctx context.Context, loanReq model.ProductLoan, pending []model.Loan, timeNow time.Time) (*issuedLoan, error) {
+	product, err := getProduct(ctx, u.Products, loanReq.ProductCode, u.DefaultProductCode)
+	if err != nil {
+		return nil, err
+	}
+
+	req := loanReq.Loan
+	if errs := validateLoan(product, &req); len(errs) > 0 {
+		return nil, apperror.Validation(errs)
+	}
 
 	interest := req.Amount * req.InterestRate / 100
-	req.ID = uuid.New().String()
-	req.TotalAmount = req.Amount + interest
-	req.Outstanding = req.TotalAmount
-	req.CreatedAt = timeNow
-	req.Status = "IN_PROGRESS"
+	req.TotalAmount = req.Amount + interest + product.AdminFee
 
-	if err = u.DB.Save(&req).Error; err != nil {
-		log.Println("[CreateBills] Failed to create loan", err)
+	if err := u.checkEligibility(ctx, req, pending, timeNow); err != nil {
 		return nil, err
 	}
 
+	req.ID = uuid.New().String()
+	req.Outstanding = req.TotalAmount
+	req.CreatedAt = timeNow
+	req.Status = model.LoanStatusInProgress
+
 	billings := make([]model.Billing, 0)
 	billsPerMonth := req.TotalAmount / float64(req.Period)
-	currentDate := timeNow.Add(7 * 24 * time.Hour)
+	currentDate := nextDueDate(timeNow, product.Frequency)
 	for i := 0; i < req.Period; i++ {
 		billings = append(billings, model.Billing{
 			ID:        uuid.New().String(),
@@ -57,107 +153,162 @@ func (u *LoanUsecase) CreateBills(req model.Loan) (*model.LoanWithBills, error)
 			CreatedAt: timeNow,
 		})
 
-		currentDate = currentDate.Add(7 * 24 * time.Hour)
-	}
-
-	if err = u.DB.Save(&billings).Error; err != nil {
-		log.Println("[CreateBills] Failed to create billings", err)
-		return nil, err
+		currentDate = nextDueDate(currentDate, product.Frequency)
 	}
 
-	resp.Loan = req
-	resp.Bills = billings
-
-	return &resp, nil
+	return &issuedLoan{LoanWithBills: model.LoanWithBills{Loan: req, Bills: billings}, ProductCode: product.Code}, nil
 }
 
-func (u *LoanUsecase) GetBills(loanID string) (*model.LoanWithBills, error) {
+func (u *LoanUsecase) GetBills(ctx context.Context, loanID string) (*model.LoanWithBills, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "LoanUsecase.GetBills", trace.WithAttributes(tracing.LoanID(loanID)))
+	defer span.End()
+
 	var err error
 	var resp model.LoanWithBills
 
-	var loan model.Loan
-	if err = u.DB.Where("id = ?", loanID).First(&loan).Error; err != nil {
-		log.Println("[GetBills] Failed to get loan", err)
+	loan, err := u.isLoanIDExist(ctx, loanID)
+	if err != nil {
+		u.logger().WarnContext(ctx, "get loan failed", "loan_id", loanID, "error", err)
 		return nil, err
 	}
 
-	var bills []model.Billing
-	if err = u.DB.Where("loan_id = ?", loanID).Find(&bills).Error; err != nil {
-		log.Println("[GetBills] Failed to get bills", err)
+	bills, err := u.Billings.GetByLoanID(ctx, loanID)
+	if err != nil {
+		u.logger().ErrorContext(ctx, "get bills failed", "loan_id", loanID, "error", err)
 		return nil, err
 	}
 
-	resp.Loan = loan
+	resp.Loan = *loan
 	resp.Bills = bills
 
 	return &resp, nil
 }
 
-func (u *LoanUsecase) GetBillStatus(loanID string) (*model.BillingStatus, error) {
-	resp := model.BillingStatus{
-		LoanID: loanID,
-	}
-	timeNow := util.GetCurrentTime()
+func (u *LoanUsecase) GetBillStatus(ctx context.Context, loanID string) (*model.BillingStatus, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "LoanUsecase.GetBillStatus", trace.WithAttributes(tracing.LoanID(loanID)))
+	defer span.End()
 
-	_, err := u.isLoanIDExist(loanID)
+	_, err := u.isLoanIDExist(ctx, loanID)
 	if err != nil {
 		return nil, err
 	}
 
-	var bills []model.Billing
-	if err := u.DB.Where("loan_id = ? AND DATE(due_date) < ? AND payment_date IS NULL", loanID, timeNow).Find(&bills).Error; err != nil {
-		log.Println("[GetBillStatus] Failed to get bills", err)
+	return u.billStatus(ctx, loanID, util.GetCurrentTime())
+}
+
+// billStatus applies the delinquency rule to the loan's bills unpaid as of now.
+func (u *LoanUsecase) billStatus(ctx context.Context, loanID string, now time.Time) (*model.BillingStatus, error) {
+	bills, err := u.Billings.GetUnpaidDueBy(ctx, loanID, now)
+	if err != nil {
+		u.logger().ErrorContext(ctx, "get unpaid bills failed", "loan_id", loanID, "error", err)
 		return nil, err
 	}
 
-	if len(bills) >= 2 {
+	return u.statusOf(loanID, bills), nil
+}
+
+// statusOf applies the delinquency rule to a loan's unpaid bills that are
+// due, oldest first.
+func (u *LoanUsecase) statusOf(loanID string, bills []model.Billing) *model.BillingStatus {
+	resp := model.BillingStatus{
+		LoanID: loanID,
+	}
+
+	if threshold := u.delinquencyThreshold(); len(bills) >= threshold {
 		resp.IsDelinquent = true
-		resp.DelinquentAt = bills[1].DueDate
+		resp.DelinquentAt = bills[threshold-1].DueDate
 	} else {
 		resp.IsDelinquent = false
 	}
 
-	return &resp, nil
+	return &resp
 }
 
-func (u *LoanUsecase) MakePayment(req model.MakePaymentRequest) (*model.Payment, error) {
+func (u *LoanUsecase) MakePayment(ctx context.Context, req model.MakePaymentRequest) (*model.Payment, error) {
+	return u.postPayment(ctx, req, u.Loans.ApplyPayments)
+}
+
+// postPayment pays the loan's oldest bill due by the payment date, posting it
+// with post.
+func (u *LoanUsecase) postPayment(ctx context.Context, req model.MakePaymentRequest, post func(context.Context, []repository.BillPayment) error) (*model.Payment, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "LoanUsecase.MakePayment", trace.WithAttributes(tracing.LoanID(req.LoanID)))
+	defer span.End()
+
+	resp, err := u.makePayment(ctx, req, post)
+	outcome := paymentOutcome(err)
+	u.Metrics.ObservePayment(outcome, req.PaymentAmount)
+	span.SetAttributes(attribute.String("billing.payment.outcome", outcome))
+	return resp, err
+}
+
+func paymentOutcome(err error) string {
+	switch {
+	case err == nil:
+		return metrics.OutcomeSuccess
+	case errors.Is(err, ErrInsufficientAmount):
+		return metrics.OutcomeInsufficientAmount
+	case errors.Is(err, ErrNoPendingBill):
+		return metrics.OutcomeNoPendingBill
+	default:
+		return metrics.OutcomeError
+	}
+}
+
+func (u *LoanUsecase) makePayment(ctx context.Context, req model.MakePaymentRequest, post func(context.Context, []repository.BillPayment) error) (*model.Payment, error) {
 	var resp model.Payment
 
-	loan, err := u.isLoanIDExist(req.LoanID) 
-	if err != nil {
+	if _, err := u.isLoanIDExist(ctx, req.LoanID); err != nil {
 		return nil, err
 	}
 
-	var bill model.Billing
-	if err := u.DB.Where("loan_id = ? AND payment_date IS NULL AND DATE(due_date) < ?", req.LoanID, req.PaymentDate).Order("due_date").First(&bill).Error; err != nil {
+	bills, err := u.Billings.GetUnpaidDueBy(ctx, req.LoanID, req.PaymentDate)
+	if err != nil {
+		u.logger().ErrorContext(ctx, "get unpaid bills failed", "loan_id", req.LoanID, "error", err)
+		return nil, err
+	}
+	if len(bills) == 0 {
 		return nil, ErrNoPendingBill
 	}
+	bill := bills[0]
 
 	if req.PaymentAmount != bill.Amount {
 		return nil, ErrInsufficientAmount
 	}
 
-	if err := u.DB.Model(&model.Billing{}).Where("id = ?", bill.ID).Update("payment_date", req.PaymentDate).Error; err != nil {
-		log.Println("[MakePayment] Failed to update bill", err)
+	payment := repository.BillPayment{LoanID: req.LoanID, BillingID: bill.ID, Amount: bill.Amount, PaymentDate: req.PaymentDate}
+	if err := post(ctx, []repository.BillPayment{payment}); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrConflict):
+			return nil, ErrBillAlreadyPaid.Wrap(err)
+		case errors.Is(err, repository.ErrDuplicate):
+			return nil, err
+		}
+		u.logger().ErrorContext(ctx, "apply payment failed", "loan_id", req.LoanID, "billing_id", bill.ID, "error", err)
 		return nil, err
 	}
 
-	status := "IN_PROGRESS"
-	if loan.Outstanding - req.PaymentAmount <= 0 {
-		status = "COMPLETED"
-	}
-
-	if err := u.DB.Model(&model.Loan{}).Where("id = ?", req.LoanID).Updates(map[string]interface{}{
-		"outstanding": gorm.Expr("outstanding - ?", bill.Amount),
-		"status": status,
-	}).Error; err != nil {
-		log.Println("[MakePayment] Failed to update loan's outstanding", err)
-		return nil, err
-	}
+	u.logger().InfoContext(ctx, "payment applied", "loan_id", req.LoanID, "billing_id", bill.ID, "amount", bill.Amount)
 
 	resp.LoanID = req.LoanID
 	resp.Amount = req.PaymentAmount
 	resp.Date = req.PaymentDate
 
 	return &resp, nil
-}
\ No newline at end of file
+}
+
+// DelinquencySummary totals the loans that GetBillStatus would currently
+// report as delinquent.
+func (u *LoanUsecase) DelinquencySummary(ctx context.Context) (repository.DelinquencySummary, error) {
+	return u.Loans.SummarizeDelinquency(ctx, repository.EndOfDay(util.GetCurrentTime()), u.delinquencyThreshold())
+}
+
+func (u *LoanUsecase) logger() *slog.Logger {
+	return logging.OrDefault(u.Logger)
+}
+
+func (u *LoanUsecase) delinquencyThreshold() int {
+	if u.DelinquencyThreshold <= 0 {
+		return defaultDelinquencyThreshold
+	}
+	return u.DelinquencyThreshold
+}
@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"billing/internal/apperror"
+	"billing/internal/model"
+	"billing/internal/tracing"
+	"billing/internal/util"
+	"billing/internal/validation"
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MaxLoanBatch caps the loan requests of one batch.
+const MaxLoanBatch = 1000
+
+// loanBatchChunk is how many loans are stored per transaction.
+const loanBatchChunk = 100
+
+// CodeBatchNotStored explains rows that passed validation but were not
+// stored because storing their chunk failed.
+const CodeBatchNotStored = "NOT_STORED"
+
+// LoanBatchRow is one loan request of a batch, numbered as in
+// model.LoanBatchResult. Err holds the field errors found while reading it.
+type LoanBatchRow struct {
+	Row  int
+	Loan model.ProductLoan
+	Err  validation.Errors
+}
+
+// CreateBillsBatch validates every row first, as CreateBills would and with
+// earlier rows counting towards each customer's limits, then stores the
+// accepted loans and their bills in chunks of loanBatchChunk, one
+// transaction per chunk. Rejected rows do not stop the batch. If a chunk
+// cannot be stored, it and every later chunk are reported as failed; earlier
+// chunks stay created. The batch's customers are locked throughout, as
+// CreateBills locks the customer of a single loan.
+func (u *LoanUsecase) CreateBillsBatch(ctx context.Context, rows []LoanBatchRow) (*model.LoanBatchReport, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "LoanUsecase.CreateBillsBatch")
+	defer span.End()
+
+	if len(rows) == 0 || len(rows) > MaxLoanBatch {
+		var errs validation.Errors
+		errs.Add("loans", validation.CodeOutOfRange, "a batch must have between 1 and %d loans", MaxLoanBatch)
+		return nil, apperror.Validation(errs)
+	}
+
+	customerIDs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		customerIDs = append(customerIDs, row.Loan.CustomerID)
+	}
+	defer u.customerLocks.lock(customerIDs...)()
+
+	report := model.LoanBatchReport{
+		Rows:    len(rows),
+		Results: make([]model.LoanBatchResult, len(rows)),
+	}
+	timeNow := util.GetCurrentTime()
+
+	var accepted []*issuedLoan
+	var acceptedRows []int
+	var pending []model.Loan
+	for i, row := range rows {
+		report.Results[i] = model.LoanBatchResult{Row: row.Row, CustomerID: row.Loan.CustomerID}
+
+		loan, err := u.batchLoan(ctx, row, pending, timeNow)
+		if err == nil {
+			accepted = append(accepted, loan)
+			acceptedRows = append(acceptedRows, i)
+			pending = append(pending, loan.Loan)
+			continue
+		}
+
+		appErr, ok := apperror.From(err)
+		if !ok {
+			u.logger().ErrorContext(ctx, "validate loan batch failed", "row", row.Row, "customer_id", row.Loan.CustomerID, "error", err)
+			return nil, err
+		}
+		report.Results[i].Status, report.Results[i].Code, report.Results[i].Message = model.BatchLoanRejected, appErr.Code, rejection(appErr)
+		report.Rejected++
+	}
+
+	var storeErr error
+	for start := 0; start < len(accepted); start += loanBatchChunk {
+		end := min(start+loanBatchChunk, len(accepted))
+		chunk := accepted[start:end]
+
+		if storeErr == nil {
+			loans := make([]model.ProductLoan, 0, len(chunk))
+			var bills []model.Billing
+			for _, loan := range chunk {
+				loans = append(loans, loan.productLoan())
+				bills = append(bills, loan.Bills...)
+			}
+			storeErr = u.Loans.CreateBatch(ctx, loans, bills)
+			if storeErr != nil {
+				u.logger().ErrorContext(ctx, "create loan batch failed", "loans", len(loans), "error", storeErr)
+			}
+		}
+
+		for j, loan := range chunk {
+			result := &report.Results[acceptedRows[start+j]]
+			if storeErr != nil {
+				result.Status, result.Code, result.Message = model.BatchLoanFailed, CodeBatchNotStored, "not created: storing the batch failed"
+				report.Failed++
+				continue
+			}
+			result.Status, result.LoanID = model.BatchLoanCreated, loan.Loan.ID
+			report.Created++
+			u.Metrics.ObserveLoanCreated()
+		}
+	}
+
+	span.SetAttributes(
+		attribute.Int("billing.batch.created", report.Created),
+		attribute.Int("billing.batch.rejected", report.Rejected),
+		attribute.Int("billing.batch.failed", report.Failed),
+	)
+	u.logger().InfoContext(ctx, "loan batch created", "rows", report.Rows,
+		"created", report.Created, "rejected", report.Rejected, "failed", report.Failed)
+	return &report, nil
+}
+
+// batchLoan checks one row as POST /bills checks a request.
+func (u *LoanUsecase) batchLoan(ctx context.Context, row LoanBatchRow, pending []model.Loan, timeNow time.Time) (*issuedLoan, error) {
+	if err := row.Err.Err(); err != nil {
+		return nil, err
+	}
+	if err := validation.Loan(&row.Loan.Loan).Err(); err != nil {
+		return nil, err
+	}
+	return u.newLoan(ctx, row.Loan, pending, timeNow)
+}
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"billing/internal/apperror"
+	"billing/internal/logging"
+	"billing/internal/model"
+	"billing/internal/payfile"
+	"billing/internal/repository"
+	"billing/internal/tracing"
+	"billing/internal/util"
+	"billing/internal/validation"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CodeDuplicateReference explains rows whose bank reference was already
+// imported, by an earlier run or earlier in the same file.
+const CodeDuplicateReference = "DUPLICATE_REFERENCE"
+
+// PaymentImportUsecase posts the payments of a bank file through MakePayment.
+type PaymentImportUsecase struct {
+	Loans   *LoanUsecase
+	Imports repository.PaymentImportRepository
+
+	// MaxPaymentLead is how far ahead of today a payment_date may be, as for
+	// POST /bills/:loan_id/payments.
+	MaxPaymentLead time.Duration
+
+	Logger *slog.Logger
+}
+
+// Import applies rows in file order and reports each one. A reference is
+// recorded in the same transaction that posts its payment, so a file can be
+// imported again after a failure, a crash or a fix without paying anything
+// twice or losing a payment. Rejected rows do not stop the import; an unexpected
+// error does, and rows applied before it stay applied.
+func (u *PaymentImportUsecase) Import(ctx context.Context, source string, rows []payfile.Row) (*model.PaymentImportReport, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "PaymentImportUsecase.Import")
+	defer span.End()
+
+	report := model.PaymentImportReport{
+		Source:  source,
+		Rows:    len(rows),
+		Results: make([]model.PaymentImportResult, 0, len(rows)),
+	}
+	seen := map[string]int{}
+
+	for _, row := range rows {
+		result := model.PaymentImportResult{
+			Line:        row.Line,
+			Reference:   row.Reference,
+			LoanID:      row.LoanID,
+			Amount:      row.Amount,
+			PaymentDate: row.PaymentDate,
+		}
+
+		if line, ok := seen[row.Reference]; ok && row.Reference != "" {
+			result.Status, result.Code = model.ImportDuplicate, CodeDuplicateReference
+			result.Message = fmt.Sprintf("reference %s is repeated from line %d", row.Reference, line)
+		} else if err := u.importRow(ctx, source, row); err == nil {
+			result.Status = model.ImportApplied
+		} else if errors.Is(err, repository.ErrDuplicate) {
+			result.Status, result.Code = model.ImportDuplicate, CodeDuplicateReference
+			result.Message = fmt.Sprintf("reference %s was already imported", row.Reference)
+		} else if appErr, ok := apperror.From(err); ok {
+			result.Status, result.Code, result.Message = model.ImportRejected, appErr.Code, rejection(appErr)
+		} else {
+			u.logger().ErrorContext(ctx, "payment import failed", "source", source, "line", row.Line, "loan_id", row.LoanID, "error", err)
+			return nil, err
+		}
+		if row.Reference != "" {
+			if _, ok := seen[row.Reference]; !ok {
+				seen[row.Reference] = row.Line
+			}
+		}
+
+		switch result.Status {
+		case model.ImportApplied:
+			report.Applied++
+		case model.ImportRejected:
+			report.Rejected++
+		case model.ImportDuplicate:
+			report.Duplicate++
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	span.SetAttributes(
+		attribute.Int("billing.import.applied", report.Applied),
+		attribute.Int("billing.import.rejected", report.Rejected),
+		attribute.Int("billing.import.duplicate", report.Duplicate),
+	)
+	u.logger().InfoContext(ctx, "payment file imported", "source", source, "rows", report.Rows,
+		"applied", report.Applied, "rejected", report.Rejected, "duplicate", report.Duplicate)
+	return &report, nil
+}
+
+// importRow validates row as the payment endpoint would and posts it together
+// with its reference.
+func (u *PaymentImportUsecase) importRow(ctx context.Context, source string, row payfile.Row) error {
+	req := model.MakePaymentRequest{
+		LoanID:        row.LoanID,
+		PaymentAmount: row.Amount,
+		PaymentDate:   row.PaymentDate,
+	}
+	if err := row.Err.Err(); err != nil {
+		return err
+	}
+	if err := validation.LoanID(row.LoanID).Err(); err != nil {
+		return err
+	}
+	validator := validation.Validator{MaxPaymentLead: u.MaxPaymentLead}
+	if err := validator.Validate(&req); err != nil {
+		return err
+	}
+
+	// Checked first so a re-imported row reads as a duplicate even when its
+	// loan has nothing left to pay; Apply repeats the check atomically.
+	applied, err := u.Imports.Applied(ctx, row.Reference)
+	if err != nil {
+		return err
+	}
+	if applied {
+		return repository.ErrDuplicate
+	}
+
+	now := util.GetCurrentTime()
+	rec := model.PaymentImport{
+		Reference:   row.Reference,
+		LoanID:      row.LoanID,
+		Amount:      row.Amount,
+		PaymentDate: row.PaymentDate,
+		Status:      model.ImportApplied,
+		Source:      source,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	_, err = u.Loans.postPayment(ctx, req, func(ctx context.Context, payments []repository.BillPayment) error {
+		return u.Imports.Apply(ctx, &rec, payments)
+	})
+	return err
+}
+
+// rejection describes why a row was refused; field errors name the field.
+func rejection(err *apperror.Error) string {
+	if len(err.Fields) > 0 {
+		return err.Fields.Error()
+	}
+	return err.Message
+}
+
+func (u *PaymentImportUsecase) logger() *slog.Logger {
+	return logging.OrDefault(u.Logger)
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"billing/internal/apperror"
+	"billing/internal/logging"
+	"billing/internal/model"
+	"billing/internal/repository"
+	"billing/internal/util"
+	"billing/internal/validation"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+const (
+	FrequencyWeekly   = "WEEKLY"
+	FrequencyBiweekly = "BIWEEKLY"
+	FrequencyMonthly  = "MONTHLY"
+
+	// DefaultProductCode is the product seeded into an empty catalogue.
+	DefaultProductCode = "FLAT-WEEKLY"
+)
+
+// DefaultProducts is the catalogue seeded into an empty database.
+var DefaultProducts = []model.Product{
+	{
+		Code:         DefaultProductCode,
+		Name:         "Flat weekly installment loan",
+		MinAmount:    500000,
+		MaxAmount:    20000000,
+		Tenors:       []int{25, 50},
+		InterestRate: 10,
+		Frequency:    FrequencyWeekly,
+	},
+}
+
+type ProductUsecase struct {
+	Products repository.ProductRepository
+	Logger   *slog.Logger
+}
+
+// SeedDefaults inserts DefaultProducts that are not in the catalogue yet.
+func (u *ProductUsecase) SeedDefaults(ctx context.Context) error {
+	timeNow := util.GetCurrentTime()
+	for _, product := range DefaultProducts {
+		product.CreatedAt = timeNow
+		if err := u.Products.CreateIfMissing(ctx, &product); err != nil {
+			logging.OrDefault(u.Logger).ErrorContext(ctx, "seed product failed", "product_code", product.Code, "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// getProduct resolves the product a loan request refers to. A request with no
+// product_code gets defaultCode when one is configured and is rejected
+// otherwise.
+func getProduct(ctx context.Context, products repository.ProductRepository, code, defaultCode string) (*model.Product, error) {
+	if code == "" {
+		code = defaultCode
+	}
+	if code == "" {
+		var errs validation.Errors
+		errs.Add("product_code", validation.CodeRequired, "product_code is required")
+		return nil, apperror.Validation(errs)
+	}
+
+	product, err := products.GetByCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			var errs validation.Errors
+			errs.Add("product_code", validation.CodeUnknownReference, "unknown product %q", code)
+			return nil, apperror.Validation(errs)
+		}
+		return nil, err
+	}
+	return product, nil
+}
+
+// validateLoan checks a loan request against its product and fills in the
+// product's defaults for fields the request left empty.
+func validateLoan(product *model.Product, req *model.Loan) validation.Errors {
+	var errs validation.Errors
+
+	if req.InterestRate == 0 {
+		req.InterestRate = product.InterestRate
+	}
+
+	if req.Amount < product.MinAmount || req.Amount > product.MaxAmount {
+		errs.Add("amount", validation.CodeOutOfRange, "amount must be between %.0f and %.0f for product %s", product.MinAmount, product.MaxAmount, product.Code)
+	}
+
+	if !slices.Contains(product.Tenors, req.Period) {
+		errs.Add("period", validation.CodeNotAllowed, "period must be one of %v for product %s", product.Tenors, product.Code)
+	}
+
+	if req.InterestRate != product.InterestRate {
+		errs.Add("interest_rate", validation.CodeNotAllowed, "interest_rate must be %g for product %s", product.InterestRate, product.Code)
+	}
+
+	return errs
+}
+
+// nextDueDate advances a due date by one installment of the given frequency.
+func nextDueDate(date time.Time, frequency string) time.Time {
+	switch frequency {
+	case FrequencyBiweekly:
+		return date.Add(14 * 24 * time.Hour)
+	case FrequencyMonthly:
+		return date.AddDate(0, 1, 0)
+	default:
+		return date.Add(7 * 24 * time.Hour)
+	}
+}
@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"billing/internal/logging"
+	"billing/internal/model"
+	"billing/internal/repository"
+	"billing/internal/tracing"
+	"billing/internal/util"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ReportUsecase answers management reporting over the whole loan book.
+type ReportUsecase struct {
+	Reports repository.ReportRepository
+	Loans   repository.LoanRepository
+
+	// DelinquencyThreshold is how many overdue bills make a loan delinquent,
+	// as for LoanUsecase.
+	DelinquencyThreshold int
+
+	Logger *slog.Logger
+}
+
+// Portfolio reports disbursements and collections over period, and the
+// book's outstanding, delinquency and portfolio at risk at its end.
+func (u *ReportUsecase) Portfolio(ctx context.Context, period model.ReportPeriod) (*model.PortfolioReport, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ReportUsecase.Portfolio")
+	defer span.End()
+
+	totals, err := u.Reports.Portfolio(ctx, repository.PortfolioQuery{
+		From:       repository.StartOfDay(period.From),
+		To:         repository.EndOfDay(period.To),
+		MinOverdue: u.delinquencyThreshold(),
+	})
+	if err != nil {
+		logging.OrDefault(u.Logger).ErrorContext(ctx, "portfolio report failed", "error", err)
+		return nil, err
+	}
+
+	return &model.PortfolioReport{
+		From:                  period.From.Format(time.DateOnly),
+		To:                    period.To.Format(time.DateOnly),
+		LoansDisbursed:        totals.LoansDisbursed,
+		TotalDisbursed:        totals.Disbursed,
+		PaymentsCollected:     totals.Payments,
+		TotalCollected:        totals.Collected,
+		ActiveLoans:           totals.ActiveLoans,
+		TotalOutstanding:      totals.Outstanding,
+		DelinquentLoans:       totals.DelinquentLoans,
+		DelinquentOutstanding: totals.DelinquentOutstanding,
+		PAR30:                 ratio(totals.Par30Outstanding, totals.Outstanding),
+		PAR90:                 ratio(totals.Par90Outstanding, totals.Outstanding),
+	}, nil
+}
+
+func ratio(part, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return part / whole
+}
+
+func (u *ReportUsecase) delinquencyThreshold() int {
+	if u.DelinquencyThreshold <= 0 {
+		return defaultDelinquencyThreshold
+	}
+	return u.DelinquencyThreshold
+}
+
+// CollectionsDue lists the bills to collect on search.Date, grouped by
+// customer and loan. A bill is due on the day MakePayment first accepts a
+// payment for it; overdue bills stay payable until paid.
+func (u *ReportUsecase) CollectionsDue(ctx context.Context, search model.DueSearch) (*model.CollectionsDue, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ReportUsecase.CollectionsDue")
+	defer span.End()
+
+	q := repository.DueQuery{Before: repository.EndOfDay(search.Date), Branch: search.Branch}
+	if !search.IncludeOverdue {
+		q.From = repository.StartOfDay(search.Date)
+	}
+	bills, err := u.Reports.ListDue(ctx, q)
+	if err != nil {
+		logging.OrDefault(u.Logger).ErrorContext(ctx, "list due bills failed", "error", err)
+		return nil, err
+	}
+
+	resp := model.CollectionsDue{
+		Date:      search.Date.Format(time.DateOnly),
+		Branch:    search.Branch,
+		Customers: []model.CollectionCustomer{},
+	}
+	for _, b := range bills {
+		n := len(resp.Customers)
+		if n == 0 || resp.Customers[n-1].CustomerID != b.CustomerID {
+			resp.Customers = append(resp.Customers, model.CollectionCustomer{
+				CustomerID: b.CustomerID,
+				Name:       b.CustomerName,
+				Phone:      b.CustomerPhone,
+				Branch:     b.CustomerBranch,
+			})
+			n++
+		}
+		customer := &resp.Customers[n-1]
+
+		m := len(customer.Loans)
+		if m == 0 || customer.Loans[m-1].LoanID != b.LoanID {
+			customer.Loans = append(customer.Loans, model.CollectionLoan{LoanID: b.LoanID})
+			m++
+		}
+		loan := &customer.Loans[m-1]
+
+		loan.Bills = append(loan.Bills, b.Billing)
+		loan.ExpectedAmount += b.Amount
+		customer.TotalAmount += b.Amount
+		resp.TotalAmount += b.Amount
+	}
+
+	return &resp, nil
+}
+
+// ExportLoans passes every loan matching search to fn, in search order and
+// without paging, stopping at fn's first error.
+func (u *ReportUsecase) ExportLoans(ctx context.Context, search model.LoanSearch, fn func(model.ProductLoan) error) error {
+	ctx, span := tracing.Tracer().Start(ctx, "ReportUsecase.ExportLoans")
+	defer span.End()
+
+	err := u.Loans.Each(ctx, repository.LoanQuery{
+		Search:           search,
+		DelinquentBefore: repository.EndOfDay(util.GetCurrentTime()),
+		MinOverdue:       u.delinquencyThreshold(),
+	}, fn)
+	if err != nil {
+		logging.OrDefault(u.Logger).ErrorContext(ctx, "export loans failed", "error", err)
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"billing/internal/apperror"
+	"billing/internal/auth"
+	"billing/internal/model"
+	"billing/internal/repository"
+	"billing/internal/tracing"
+	"billing/internal/util"
+	"billing/internal/validation"
+	"context"
+)
+
+// SearchLoans returns one page of the loans matching search. Borrowers only
+// ever see their own customer's loans, whatever customer_id they ask for.
+func (u *LoanUsecase) SearchLoans(ctx context.Context, search model.LoanSearch) (*model.LoanPage, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "LoanUsecase.SearchLoans")
+	defer span.End()
+
+	resp := model.LoanPage{Loans: []model.ProductLoan{}}
+
+	if principal, ok := auth.FromContext(ctx); ok {
+		if scope, scoped := principal.CustomerScope(); scoped {
+			if search.CustomerID != "" && search.CustomerID != scope {
+				return &resp, nil
+			}
+			search.CustomerID = scope
+		}
+	}
+
+	q := repository.LoanQuery{
+		Search:           search,
+		DelinquentBefore: repository.EndOfDay(util.GetCurrentTime()),
+		MinOverdue:       u.delinquencyThreshold(),
+	}
+	if search.Cursor != "" {
+		after, err := repository.DecodeCursor(search.Cursor, search.Sort, search.Desc)
+		if err != nil {
+			var errs validation.Errors
+			errs.Add("cursor", validation.CodeInvalidFormat, "cursor is not valid for this sort and order")
+			return nil, apperror.Validation(errs)
+		}
+		q.After = after
+	}
+	// One extra loan tells whether another page follows.
+	q.Search.Limit = search.Limit + 1
+
+	loans, err := u.Loans.Search(ctx, q)
+	if err != nil {
+		u.logger().ErrorContext(ctx, "search loans failed", "error", err)
+		return nil, err
+	}
+
+	if len(loans) > search.Limit {
+		loans = loans[:search.Limit]
+		resp.NextCursor = repository.CursorAfter(loans[len(loans)-1].Loan, search.Sort, search.Desc).Encode()
+	}
+	resp.Loans = append(resp.Loans, loans...)
+
+	return &resp, nil
+}
@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"billing/internal/model"
+	"billing/internal/repository"
+	"billing/internal/tracing"
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Statement describes the loan as it stood at the end of asOf: bills paid
+// later count as unpaid, and delinquency follows the GetBillStatus rule
+// applied on that day.
+func (u *LoanUsecase) Statement(ctx context.Context, loanID string, asOf time.Time) (*model.LoanStatement, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "LoanUsecase.Statement", trace.WithAttributes(tracing.LoanID(loanID)))
+	defer span.End()
+
+	schedule, err := u.GetBills(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	productCode, err := u.Loans.ProductCode(ctx, loanID)
+	if err != nil {
+		u.logger().ErrorContext(ctx, "get loan product failed", "loan_id", loanID, "error", err)
+		return nil, err
+	}
+
+	resp := model.LoanStatement{LoanWithBills: *schedule, ProductCode: productCode, AsOf: asOf}
+	end := repository.EndOfDay(asOf)
+	var missed []model.Billing
+	for _, b := range schedule.Bills {
+		if b.PaymentDate != nil && b.PaymentDate.Before(end) {
+			resp.PaymentsReceived++
+			resp.TotalPaid += b.Amount
+			continue
+		}
+		resp.Outstanding += b.Amount
+		if b.DueDate.Before(end) {
+			missed = append(missed, b)
+		}
+	}
+
+	if threshold := u.delinquencyThreshold(); len(missed) >= threshold {
+		resp.IsDelinquent = true
+		resp.DelinquentAt = &missed[threshold-1].DueDate
+	}
+
+	return &resp, nil
+}
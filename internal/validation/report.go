@@ -0,0 +1,78 @@
+package validation
+
+import (
+	"billing/internal/model"
+	"billing/internal/util"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportPeriod parses the from and to query parameters of a report. to
+// defaults to today and from to the first day of to's month.
+func ReportPeriod(query url.Values) (model.ReportPeriod, Errors) {
+	var errs Errors
+
+	to := parseDate(&errs, query, "to")
+	if to == nil {
+		today := today()
+		to = &today
+	}
+	from := parseDate(&errs, query, "from")
+	if from == nil {
+		first := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.Local)
+		from = &first
+	}
+	if to.Before(*from) {
+		errs.Add("to", CodeOutOfRange, "to must not be before from")
+	}
+
+	return model.ReportPeriod{From: *from, To: *to}, errs
+}
+
+// CollectionsDue parses the GET /collections/due query; date defaults to
+// today.
+func CollectionsDue(query url.Values) (model.DueSearch, Errors) {
+	var errs Errors
+	search := model.DueSearch{Branch: strings.TrimSpace(query.Get("branch"))}
+
+	search.Date = today()
+	if date := parseDate(&errs, query, "date"); date != nil {
+		search.Date = *date
+	}
+
+	if search.Branch != "" && !idPattern.MatchString(search.Branch) {
+		errs.Add("branch", CodeInvalidFormat, "branch may only contain letters, digits, '-' and '_'")
+	}
+
+	if v := query.Get("include_overdue"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs.Add("include_overdue", CodeInvalidFormat, "include_overdue must be true or false")
+		}
+		search.IncludeOverdue = b
+	}
+
+	return search, errs
+}
+
+// today is the current day as parseDate would read it.
+func today() time.Time {
+	now := util.GetCurrentTime().In(time.Local)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// StatementDate parses the as_of query parameter of a loan statement, which
+// defaults to today and may not be in the future.
+func StatementDate(query url.Values) (time.Time, Errors) {
+	var errs Errors
+	asOf := today()
+	if date := parseDate(&errs, query, "as_of"); date != nil {
+		if date.After(asOf) {
+			errs.Add("as_of", CodeInFuture, "as_of must not be in the future")
+		}
+		asOf = *date
+	}
+	return asOf, errs
+}
@@ -0,0 +1,112 @@
+package validation
+
+import (
+	"billing/internal/model"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+var (
+	loanStatuses = []string{model.LoanStatusInProgress, model.LoanStatusDelinquent, model.LoanStatusCompleted}
+	loanSorts    = []string{model.LoanSortCreatedAt, model.LoanSortOutstanding, model.LoanSortAmount}
+)
+
+// LoanSearch parses the GET /loans query. Results are newest first unless
+// sort names another column; a leading '-' sorts descending.
+func LoanSearch(query url.Values) (model.LoanSearch, Errors) {
+	var errs Errors
+	search := model.LoanSearch{
+		Status:      strings.TrimSpace(query.Get("status")),
+		CustomerID:  strings.TrimSpace(query.Get("customer_id")),
+		ProductCode: strings.TrimSpace(query.Get("product_code")),
+		Sort:        model.LoanSortCreatedAt,
+		Desc:        true,
+		Limit:       DefaultSearchLimit,
+		Cursor:      strings.TrimSpace(query.Get("cursor")),
+	}
+
+	if search.Status != "" && !slices.Contains(loanStatuses, search.Status) {
+		errs.Add("status", CodeNotAllowed, "status must be one of %s", strings.Join(loanStatuses, ", "))
+	}
+	if search.CustomerID != "" {
+		errs = append(errs, CustomerID(search.CustomerID)...)
+	}
+	if search.ProductCode != "" && !idPattern.MatchString(search.ProductCode) {
+		errs.Add("product_code", CodeInvalidFormat, "product_code may only contain letters, digits, '-' and '_'")
+	}
+
+	search.CreatedFrom = parseDate(&errs, query, "created_from")
+	search.CreatedTo = parseDate(&errs, query, "created_to")
+	if search.CreatedFrom != nil && search.CreatedTo != nil && search.CreatedTo.Before(*search.CreatedFrom) {
+		errs.Add("created_to", CodeOutOfRange, "created_to must not be before created_from")
+	}
+
+	if v := query.Get("delinquent"); v != "" {
+		if b, err := strconv.ParseBool(v); err != nil {
+			errs.Add("delinquent", CodeInvalidFormat, "delinquent must be true or false")
+		} else {
+			search.Delinquent = &b
+		}
+	}
+
+	search.MinOutstanding = parseAmount(&errs, query, "min_outstanding")
+	search.MaxOutstanding = parseAmount(&errs, query, "max_outstanding")
+	if search.MinOutstanding != nil && search.MaxOutstanding != nil && *search.MaxOutstanding < *search.MinOutstanding {
+		errs.Add("max_outstanding", CodeOutOfRange, "max_outstanding must not be below min_outstanding")
+	}
+
+	if v := query.Get("sort"); v != "" {
+		field, desc := strings.CutPrefix(v, "-")
+		if !slices.Contains(loanSorts, field) {
+			errs.Add("sort", CodeNotAllowed, "sort must be one of %s, optionally prefixed with '-'", strings.Join(loanSorts, ", "))
+		}
+		search.Sort, search.Desc = field, desc
+	}
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxSearchLimit {
+			errs.Add("limit", CodeOutOfRange, "limit must be between 1 and %d", MaxSearchLimit)
+		}
+		search.Limit = n
+	}
+
+	return search, errs
+}
+
+func parseDate(errs *Errors, query url.Values, field string) *time.Time {
+	v := query.Get(field)
+	if v == "" {
+		return nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+	if err != nil {
+		errs.Add(field, CodeInvalidFormat, "%s must be a date formatted as YYYY-MM-DD", field)
+		return nil
+	}
+	return &t
+}
+
+func parseAmount(errs *Errors, query url.Values, field string) *float64 {
+	v := query.Get(field)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		errs.Add(field, CodeInvalidFormat, "%s must be a number", field)
+		return nil
+	}
+	if n < 0 {
+		errs.Add(field, CodeOutOfRange, "%s must not be negative", field)
+	}
+	return &n
+}
@@ -0,0 +1,51 @@
+// Package validation checks incoming requests and reports every rejected
+// field with a stable, machine-readable code.
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Codes are part of the API contract; clients match on them, so never rename one.
+const (
+	CodeRequired         = "REQUIRED"
+	CodeMustBePositive   = "MUST_BE_POSITIVE"
+	CodeOutOfRange       = "OUT_OF_RANGE"
+	CodeNotAllowed       = "NOT_ALLOWED"
+	CodeInFuture         = "IN_FUTURE"
+	CodeTooFarAhead      = "TOO_FAR_AHEAD"
+	CodeInvalidFormat    = "INVALID_FORMAT"
+	CodeUnknownReference = "UNKNOWN_REFERENCE"
+)
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors collects every field error found in one request.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, f := range e {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Add records a rejected field.
+func (e *Errors) Add(field, code, format string, args ...any) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns e as an error, or nil when nothing was rejected.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
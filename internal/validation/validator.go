@@ -0,0 +1,209 @@
+package validation
+
+import (
+	"billing/internal/model"
+	"billing/internal/util"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+const (
+	MinPeriod       = 1
+	MaxPeriod       = 260
+	MaxInterestRate = 100
+
+	// By default an installment may be paid up to one weekly cycle before it
+	// falls due, so this week's bill can be settled at any time during the week.
+	defaultMaxPaymentLead = 7 * 24 * time.Hour
+)
+
+// Validator plugs the request rules into echo.Context.Validate.
+type Validator struct {
+	// MaxPaymentLead is how far ahead of now a payment_date may be, so that
+	// an installment can be paid before it falls due.
+	MaxPaymentLead time.Duration
+}
+
+func (v *Validator) Validate(i interface{}) error {
+	switch req := i.(type) {
+	case *model.Loan:
+		return Loan(req).Err()
+	case *model.ProductLoan:
+		return Loan(&req.Loan).Err()
+	case *model.MakePaymentRequest:
+		return Payment(req, v.maxPaymentLead()).Err()
+	case *model.GroupPaymentRequest:
+		return Payment(&model.MakePaymentRequest{PaymentAmount: req.PaymentAmount, PaymentDate: req.PaymentDate}, v.maxPaymentLead()).Err()
+	case *model.CreateCustomerRequest:
+		return Customer(req).Err()
+	case *model.CreateGroupRequest:
+		return Group(req).Err()
+	case *model.AddGroupLoansRequest:
+		return GroupLoanIDs(req.LoanIDs).Err()
+	default:
+		return nil
+	}
+}
+
+// Loan checks the product-independent rules of a loan request.
+func Loan(req *model.Loan) Errors {
+	var errs Errors
+
+	if req.CustomerID == "" || req.CustomerID == "0" {
+		errs.Add("customer_id", CodeRequired, "customer_id is required")
+	}
+
+	if req.Amount <= 0 {
+		errs.Add("amount", CodeMustBePositive, "amount must be greater than 0")
+	}
+
+	if req.Period < MinPeriod || req.Period > MaxPeriod {
+		errs.Add("period", CodeOutOfRange, "period must be between %d and %d", MinPeriod, MaxPeriod)
+	}
+
+	if req.InterestRate < 0 || req.InterestRate > MaxInterestRate {
+		errs.Add("interest_rate", CodeOutOfRange, "interest_rate must be between 0 and %d", MaxInterestRate)
+	}
+
+	return errs
+}
+
+func (v *Validator) maxPaymentLead() time.Duration {
+	if v.MaxPaymentLead <= 0 {
+		return defaultMaxPaymentLead
+	}
+	return v.MaxPaymentLead
+}
+
+// Payment checks a payment request. payment_date may be in the future, by up
+// to maxLead, since an installment may be paid before it falls due.
+func Payment(req *model.MakePaymentRequest, maxLead time.Duration) Errors {
+	var errs Errors
+
+	if req.PaymentAmount <= 0 {
+		errs.Add("payment_amount", CodeMustBePositive, "payment_amount must be greater than 0")
+	}
+
+	if req.PaymentDate.IsZero() {
+		errs.Add("payment_date", CodeRequired, "payment_date is required")
+	} else if req.PaymentDate.After(util.GetCurrentTime().Add(maxLead)) {
+		errs.Add("payment_date", CodeTooFarAhead, "payment_date must not be more than %s ahead", leadDays(maxLead))
+	}
+
+	return errs
+}
+
+// leadDays describes a payment lead in whole days, e.g. "7 days".
+func leadDays(lead time.Duration) string {
+	days := int(lead / (24 * time.Hour))
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// LoanID checks a loan_id path parameter.
+func LoanID(loanID string) Errors {
+	var errs Errors
+
+	if loanID == "" || loanID == "0" {
+		errs.Add("loan_id", CodeRequired, "loan_id is required")
+	} else if !idPattern.MatchString(loanID) {
+		errs.Add("loan_id", CodeInvalidFormat, "loan_id may only contain letters, digits, '-' and '_'")
+	}
+
+	return errs
+}
+
+// CustomerID checks a customer_id path parameter.
+func CustomerID(customerID string) Errors {
+	var errs Errors
+
+	if customerID == "" {
+		errs.Add("customer_id", CodeRequired, "customer_id is required")
+	} else if !idPattern.MatchString(customerID) {
+		errs.Add("customer_id", CodeInvalidFormat, "customer_id may only contain letters, digits, '-' and '_'")
+	}
+
+	return errs
+}
+
+// Customer checks a customer registration.
+func Customer(req *model.CreateCustomerRequest) Errors {
+	var errs Errors
+
+	if req.ID == "" {
+		errs.Add("id", CodeRequired, "id is required")
+	} else if !idPattern.MatchString(req.ID) {
+		errs.Add("id", CodeInvalidFormat, "id may only contain letters, digits, '-' and '_'")
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		errs.Add("name", CodeRequired, "name is required")
+	}
+	switch req.KYCStatus {
+	case "", model.KYCStatusPending, model.KYCStatusVerified, model.KYCStatusRejected:
+	default:
+		errs.Add("kyc_status", CodeNotAllowed, "kyc_status must be %s, %s or %s", model.KYCStatusPending, model.KYCStatusVerified, model.KYCStatusRejected)
+	}
+	if req.Branch != "" && !idPattern.MatchString(req.Branch) {
+		errs.Add("branch", CodeInvalidFormat, "branch may only contain letters, digits, '-' and '_'")
+	}
+
+	return errs
+}
+
+// MaxGroupLoans caps the loans of one group request.
+const MaxGroupLoans = 100
+
+// GroupID checks a group_id path parameter.
+func GroupID(groupID string) Errors {
+	var errs Errors
+
+	if groupID == "" {
+		errs.Add("group_id", CodeRequired, "group_id is required")
+	} else if !idPattern.MatchString(groupID) {
+		errs.Add("group_id", CodeInvalidFormat, "group_id may only contain letters, digits, '-' and '_'")
+	}
+
+	return errs
+}
+
+// Group checks a new group; it needs a name and at least one loan.
+func Group(req *model.CreateGroupRequest) Errors {
+	var errs Errors
+
+	if strings.TrimSpace(req.Name) == "" {
+		errs.Add("name", CodeRequired, "name is required")
+	}
+	if req.Branch != "" && !idPattern.MatchString(req.Branch) {
+		errs.Add("branch", CodeInvalidFormat, "branch may only contain letters, digits, '-' and '_'")
+	}
+
+	return append(errs, GroupLoanIDs(req.LoanIDs)...)
+}
+
+// GroupLoanIDs checks the loan_ids of a group request.
+func GroupLoanIDs(loanIDs []string) Errors {
+	var errs Errors
+
+	if len(loanIDs) == 0 || len(loanIDs) > MaxGroupLoans {
+		errs.Add("loan_ids", CodeOutOfRange, "loan_ids must list between 1 and %d loans", MaxGroupLoans)
+		return errs
+	}
+	seen := map[string]bool{}
+	for _, id := range loanIDs {
+		switch {
+		case !idPattern.MatchString(id):
+			errs.Add("loan_ids", CodeInvalidFormat, "loan_id %q may only contain letters, digits, '-' and '_'", id)
+		case seen[id]:
+			errs.Add("loan_ids", CodeNotAllowed, "loan_id %s is listed twice", id)
+		}
+		seen[id] = true
+	}
+
+	return errs
+}
@@ -1,34 +1,83 @@
 package db
 
 import (
-	// "os"
+	"fmt"
+	"strings"
 
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Config selects the database driver and how to reach it. For SQLite the DSN
+// is the database file path; for PostgreSQL it is a libpq connection string
+// or URL.
+type Config struct {
+	Driver string `json:"driver"`
+	DSN    string `json:"dsn"`
+}
+
+// Open connects to the database described by cfg, with every statement traced
+// and failed or slow statements logged through slog without their values.
+func Open(cfg Config) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case DriverSQLite:
+		dialector = sqlite.Open(withForeignKeys(cfg.DSN))
+	case DriverPostgres:
+		if cfg.DSN == "" {
+			return nil, fmt.Errorf("db: %s driver requires a DSN", cfg.Driver)
+		}
+		dialector = postgres.Open(cfg.DSN)
+	default:
+		return nil, fmt.Errorf("db: unsupported driver %q", cfg.Driver)
+	}
+
+	conn, err := gorm.Open(dialector, &gorm.Config{Logger: NewLogger(nil)})
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Use(TracingPlugin{}); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// withForeignKeys turns on SQLite's foreign key enforcement, which is off by
+// default, for every connection in the pool.
+func withForeignKeys(dsn string) string {
+	if strings.Contains(dsn, "_foreign_keys") || strings.Contains(dsn, "_fk=") {
+		return dsn
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&_foreign_keys=on"
+	}
+	return dsn + "?_foreign_keys=on"
+}
+
 var db *gorm.DB
 
 // PLEASE USE SQLITE, WE USE GORM TO MAKE IT EASIER FOR YOU, BUT YOU CAN CHANGE IT
-// InitAndMigrate initializes the database connection and creates required tables.
-func InitAndMigrate(models ...interface{}) (*gorm.DB, error) {
+// InitAndMigrate initializes the database connection and applies pending migrations.
+func InitAndMigrate(cfg Config) (*gorm.DB, error) {
 	if db != nil {
 		return db, nil
 	}
 
-	var err error
-	// Remove existing DB file if it exists
-	//_ = os.Remove("amartha.db")
-
-	db, err = gorm.Open(sqlite.Open("amartha.db"), &gorm.Config{})
+	conn, err := Open(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(models...)
-	if err != nil {
+	if _, err = MigrateUp(conn); err != nil {
 		return nil, err
 	}
 
+	db = conn
 	return db, nil
 }
@@ -0,0 +1,349 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migrations live in migrations/<driver>/<version>_<name>.sql using dbmate's
+// layout: a "-- migrate:up" section followed by a "-- migrate:down" section.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+const (
+	markerUp   = "-- migrate:up"
+	markerDown = "-- migrate:down"
+)
+
+type Migration struct {
+	Version string
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// schemaMigration is one row of the schema-version table.
+type schemaMigration struct {
+	Version   string `gorm:"primaryKey"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrations returns the migrations for driver, oldest first.
+func Migrations(driver string) ([]Migration, error) {
+	dir := path.Join("migrations", driver)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("db: no migrations for driver %q: %w", driver, err)
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		version, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("db: migration %s is not named <version>_<name>.sql", entry.Name())
+		}
+
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		up, down, err := parseMigration(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("db: migration %s: %w", entry.Name(), err)
+		}
+
+		migrations = append(migrations, Migration{Version: version, Name: name, Up: up, Down: down})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func parseMigration(content string) (up, down string, err error) {
+	_, rest, ok := strings.Cut(content, markerUp)
+	if !ok {
+		return "", "", fmt.Errorf("missing %q", markerUp)
+	}
+	up, down, ok = strings.Cut(rest, markerDown)
+	if !ok {
+		return "", "", fmt.Errorf("missing %q", markerDown)
+	}
+	return strings.TrimSpace(up), strings.TrimSpace(down), nil
+}
+
+// LatestVersion is the version the schema is at once every migration for
+// driver has been applied.
+func LatestVersion(driver string) (string, error) {
+	migrations, err := Migrations(driver)
+	if err != nil {
+		return "", err
+	}
+	if len(migrations) == 0 {
+		return "", nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// CurrentVersion is the newest migration applied to conn, or "" for an empty
+// database.
+func CurrentVersion(conn *gorm.DB) (string, error) {
+	if !conn.Migrator().HasTable(&schemaMigration{}) {
+		return "", nil
+	}
+
+	var version string
+	err := conn.Model(&schemaMigration{}).Select("COALESCE(MAX(version), '')").Scan(&version).Error
+	return version, err
+}
+
+// MigrateUp applies every pending migration in order, each in its own
+// transaction, and returns the versions it applied. A database created by
+// AutoMigrate is adopted first, see adoptLegacySchema.
+func MigrateUp(conn *gorm.DB) ([]string, error) {
+	applied, err := adoptLegacySchema(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := Status(conn)
+	if err != nil {
+		return applied, err
+	}
+
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			continue
+		}
+
+		m := status.Migration
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := execScript(tx, m.Up); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("db: migration %s_%s up: %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m.Version)
+	}
+
+	return applied, nil
+}
+
+// legacyBaseline is the newest migration whose tables the schema created by
+// GORM's AutoMigrate, before versioned migrations, already has.
+const legacyBaseline = "20261019000003"
+
+// Rows of the AutoMigrate schema are moved aside while the tables are rebuilt,
+// then copied back with the columns it lacks filled in. Its loans predate the
+// product catalogue and all follow the original flat weekly terms, so they
+// get that product, as seeded by usecase.DefaultProducts.
+const (
+	legacyStash = `
+CREATE TABLE legacy_loans AS SELECT * FROM loans;
+CREATE TABLE legacy_billings AS SELECT * FROM billings;
+DROP TABLE billings;
+DROP TABLE loans`
+
+	legacyRestore = `
+INSERT INTO products (code, name, min_amount, max_amount, tenors, interest_rate, frequency, admin_fee, created_at)
+VALUES ('FLAT-WEEKLY', 'Flat weekly installment loan', 500000, 20000000, '[25,50]', 10, 'WEEKLY', 0, CURRENT_TIMESTAMP);
+INSERT INTO loans (id, customer_id, product_code, name, period, amount, interest_rate, total_amount, outstanding, status, created_at)
+SELECT id, COALESCE(customer_id, ''), 'FLAT-WEEKLY', COALESCE(name, ''), COALESCE(period, 0), COALESCE(amount, 0),
+  COALESCE(interest_rate, 0), COALESCE(total_amount, 0), COALESCE(outstanding, 0), COALESCE(status, ''),
+  COALESCE(created_at, CURRENT_TIMESTAMP)
+FROM legacy_loans;
+INSERT INTO billings (id, loan_id, sequence, date, due_date, payment_date, amount, created_at)
+SELECT id, loan_id, sequence, date, due_date, payment_date, amount, created_at FROM legacy_billings;
+DROP TABLE legacy_billings;
+DROP TABLE legacy_loans`
+)
+
+// adoptLegacySchema brings a database created by AutoMigrate, which has loans
+// and billings but no schema_migrations table, under the migrations: in one
+// transaction it rebuilds both tables as the migrations up to legacyBaseline
+// define them, keeping every row, and records those migrations as applied.
+// It returns the versions it recorded, or none for any other database.
+func adoptLegacySchema(conn *gorm.DB) ([]string, error) {
+	if conn.Migrator().HasTable(&schemaMigration{}) || !conn.Migrator().HasTable("loans") {
+		return nil, nil
+	}
+
+	migrations, err := Migrations(conn.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	var adopted []string
+	err = conn.Transaction(func(tx *gorm.DB) error {
+		if err := execScript(tx, legacyStash); err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if m.Version > legacyBaseline {
+				break
+			}
+			if err := execScript(tx, m.Up); err != nil {
+				return fmt.Errorf("%s_%s: %w", m.Version, m.Name, err)
+			}
+			adopted = append(adopted, m.Version)
+		}
+		if err := execScript(tx, legacyRestore); err != nil {
+			return err
+		}
+
+		if err := ensureSchemaTable(tx); err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, version := range adopted {
+			if err := tx.Create(&schemaMigration{Version: version, AppliedAt: now}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("db: adopt AutoMigrate schema: %w", err)
+	}
+	return adopted, nil
+}
+
+// MigrateDown rolls back the newest steps applied migrations and returns the
+// versions it rolled back.
+func MigrateDown(conn *gorm.DB, steps int) ([]string, error) {
+	statuses, err := Status(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var rolledBack []string
+	for i := len(statuses) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+		if statuses[i].AppliedAt == nil {
+			continue
+		}
+
+		m := statuses[i].Migration
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := execScript(tx, m.Down); err != nil {
+				return err
+			}
+			return tx.Where("version = ?", m.Version).Delete(&schemaMigration{}).Error
+		})
+		if err != nil {
+			return rolledBack, fmt.Errorf("db: migration %s_%s down: %w", m.Version, m.Name, err)
+		}
+		rolledBack = append(rolledBack, m.Version)
+	}
+
+	return rolledBack, nil
+}
+
+// Status lists every known migration and when it was applied to conn.
+func Status(conn *gorm.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations(conn.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ensureSchemaTable(conn); err != nil {
+		return nil, err
+	}
+
+	var rows []schemaMigration
+	if err := conn.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	appliedAt := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		appliedAt[row.Version] = row.AppliedAt
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Migration: m}
+		if at, ok := appliedAt[m.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func ensureSchemaTable(conn *gorm.DB) error {
+	return conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+  version TEXT PRIMARY KEY,
+  applied_at ` + timestampType(conn) + ` NOT NULL
+)`).Error
+}
+
+func timestampType(conn *gorm.DB) string {
+	if conn.Dialector.Name() == DriverPostgres {
+		return "TIMESTAMPTZ"
+	}
+	return "DATETIME"
+}
+
+// execScript runs each ";"-terminated statement of a migration section.
+func execScript(tx *gorm.DB, script string) error {
+	for _, stmt := range strings.Split(script, ";") {
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" {
+			continue
+		}
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckReady pings conn and confirms every migration has been applied.
+func CheckReady(ctx context.Context, conn *gorm.DB) error {
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return err
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("db: ping: %w", err)
+	}
+
+	current, err := CurrentVersion(conn.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	latest, err := LatestVersion(conn.Dialector.Name())
+	if err != nil {
+		return err
+	}
+	if current != latest {
+		return fmt.Errorf("db: schema at version %q, expected %q", current, latest)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+-- migrate:up
+CREATE TABLE products (
+  code TEXT PRIMARY KEY,
+  name TEXT NOT NULL DEFAULT '',
+  min_amount DOUBLE PRECISION NOT NULL,
+  max_amount DOUBLE PRECISION NOT NULL,
+  tenors TEXT NOT NULL,
+  interest_rate DOUBLE PRECISION NOT NULL,
+  frequency TEXT NOT NULL,
+  admin_fee DOUBLE PRECISION NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+-- migrate:down
+DROP TABLE products;
@@ -0,0 +1,19 @@
+-- migrate:up
+CREATE TABLE loans (
+  id TEXT PRIMARY KEY,
+  customer_id TEXT NOT NULL,
+  product_code TEXT NOT NULL REFERENCES products (code),
+  name TEXT NOT NULL DEFAULT '',
+  period BIGINT NOT NULL,
+  amount DOUBLE PRECISION NOT NULL,
+  interest_rate DOUBLE PRECISION NOT NULL,
+  total_amount DOUBLE PRECISION NOT NULL,
+  outstanding DOUBLE PRECISION NOT NULL,
+  status TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_loans_customer_id ON loans (customer_id);
+
+-- migrate:down
+DROP TABLE loans;
@@ -0,0 +1,29 @@
+-- migrate:up
+CREATE TABLE batch_runs (
+  id TEXT PRIMARY KEY,
+  business_date TEXT NOT NULL UNIQUE,
+  status TEXT NOT NULL,
+  last_loan_id TEXT NOT NULL DEFAULT '',
+  loans_processed BIGINT NOT NULL DEFAULT 0,
+  loans_delinquent BIGINT NOT NULL DEFAULT 0,
+  status_changes BIGINT NOT NULL DEFAULT 0,
+  penalties_created BIGINT NOT NULL DEFAULT 0,
+  penalty_amount DOUBLE PRECISION NOT NULL DEFAULT 0,
+  started_at TIMESTAMPTZ NOT NULL,
+  finished_at TIMESTAMPTZ
+);
+
+CREATE TABLE penalties (
+  id TEXT PRIMARY KEY,
+  loan_id TEXT NOT NULL REFERENCES loans (id),
+  billing_id TEXT NOT NULL UNIQUE REFERENCES billings (id),
+  amount DOUBLE PRECISION NOT NULL,
+  date TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_penalties_loan_id ON penalties (loan_id);
+
+-- migrate:down
+DROP TABLE penalties;
+DROP TABLE batch_runs;
@@ -0,0 +1,17 @@
+-- migrate:up
+-- loans.customer_id has no foreign key: loans created before customers were
+-- recorded keep their opaque customer IDs.
+CREATE TABLE customers (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  phone TEXT NOT NULL DEFAULT '',
+  kyc_status TEXT NOT NULL,
+  branch TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_customers_branch ON customers (branch);
+
+-- migrate:down
+DROP TABLE customers;
@@ -0,0 +1,25 @@
+-- migrate:up
+-- Every GET /loans sort is keyset-paginated on (column, id) and the filters that
+-- narrow most lead their own indexes.
+CREATE INDEX idx_loans_created_at_id ON loans (created_at, id);
+CREATE INDEX idx_loans_outstanding_id ON loans (outstanding, id);
+CREATE INDEX idx_loans_amount_id ON loans (amount, id);
+CREATE INDEX idx_loans_status_created_at_id ON loans (status, created_at, id);
+CREATE INDEX idx_loans_product_code_created_at_id ON loans (product_code, created_at, id);
+
+-- Replaces idx_loans_customer_id, which it prefixes.
+CREATE INDEX idx_loans_customer_id_created_at_id ON loans (customer_id, created_at, id);
+DROP INDEX idx_loans_customer_id;
+
+-- The delinquent filter counts each loan's unpaid bills due before today.
+CREATE INDEX idx_billings_unpaid_due_date ON billings (due_date, loan_id) WHERE payment_date IS NULL;
+
+-- migrate:down
+DROP INDEX idx_billings_unpaid_due_date;
+CREATE INDEX idx_loans_customer_id ON loans (customer_id);
+DROP INDEX idx_loans_customer_id_created_at_id;
+DROP INDEX idx_loans_product_code_created_at_id;
+DROP INDEX idx_loans_status_created_at_id;
+DROP INDEX idx_loans_amount_id;
+DROP INDEX idx_loans_outstanding_id;
+DROP INDEX idx_loans_created_at_id;
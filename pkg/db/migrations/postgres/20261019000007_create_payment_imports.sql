@@ -0,0 +1,18 @@
+-- migrate:up
+-- One row per bank reference ever imported. A reference is claimed before its
+-- payment is posted, so importing the same file again cannot pay twice.
+CREATE TABLE payment_imports (
+  reference TEXT PRIMARY KEY,
+  loan_id TEXT NOT NULL,
+  amount DOUBLE PRECISION NOT NULL,
+  payment_date TIMESTAMPTZ NOT NULL,
+  status TEXT NOT NULL,
+  source TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_payment_imports_loan_id ON payment_imports (loan_id);
+
+-- migrate:down
+DROP TABLE payment_imports;
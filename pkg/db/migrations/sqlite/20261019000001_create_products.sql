@@ -0,0 +1,15 @@
+-- migrate:up
+CREATE TABLE products (
+  code TEXT PRIMARY KEY,
+  name TEXT NOT NULL DEFAULT '',
+  min_amount REAL NOT NULL,
+  max_amount REAL NOT NULL,
+  tenors TEXT NOT NULL,
+  interest_rate REAL NOT NULL,
+  frequency TEXT NOT NULL,
+  admin_fee REAL NOT NULL DEFAULT 0,
+  created_at DATETIME NOT NULL
+);
+
+-- migrate:down
+DROP TABLE products;
@@ -0,0 +1,19 @@
+-- migrate:up
+CREATE TABLE loans (
+  id TEXT PRIMARY KEY,
+  customer_id TEXT NOT NULL,
+  product_code TEXT NOT NULL REFERENCES products (code),
+  name TEXT NOT NULL DEFAULT '',
+  period INTEGER NOT NULL,
+  amount REAL NOT NULL,
+  interest_rate REAL NOT NULL,
+  total_amount REAL NOT NULL,
+  outstanding REAL NOT NULL,
+  status TEXT NOT NULL,
+  created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_loans_customer_id ON loans (customer_id);
+
+-- migrate:down
+DROP TABLE loans;
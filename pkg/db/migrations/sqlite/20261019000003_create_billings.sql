@@ -0,0 +1,18 @@
+-- migrate:up
+CREATE TABLE billings (
+  id TEXT PRIMARY KEY,
+  loan_id TEXT NOT NULL REFERENCES loans (id),
+  sequence INTEGER NOT NULL,
+  date DATETIME NOT NULL,
+  due_date DATETIME NOT NULL,
+  payment_date DATETIME,
+  amount REAL NOT NULL,
+  created_at DATETIME NOT NULL,
+  UNIQUE (loan_id, sequence)
+);
+
+CREATE INDEX idx_billings_loan_id_due_date ON billings (loan_id, due_date);
+CREATE INDEX idx_billings_loan_id_payment_date ON billings (loan_id, payment_date);
+
+-- migrate:down
+DROP TABLE billings;
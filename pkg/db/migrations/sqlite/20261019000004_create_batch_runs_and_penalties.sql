@@ -0,0 +1,29 @@
+-- migrate:up
+CREATE TABLE batch_runs (
+  id TEXT PRIMARY KEY,
+  business_date TEXT NOT NULL UNIQUE,
+  status TEXT NOT NULL,
+  last_loan_id TEXT NOT NULL DEFAULT '',
+  loans_processed INTEGER NOT NULL DEFAULT 0,
+  loans_delinquent INTEGER NOT NULL DEFAULT 0,
+  status_changes INTEGER NOT NULL DEFAULT 0,
+  penalties_created INTEGER NOT NULL DEFAULT 0,
+  penalty_amount REAL NOT NULL DEFAULT 0,
+  started_at DATETIME NOT NULL,
+  finished_at DATETIME
+);
+
+CREATE TABLE penalties (
+  id TEXT PRIMARY KEY,
+  loan_id TEXT NOT NULL REFERENCES loans (id),
+  billing_id TEXT NOT NULL UNIQUE REFERENCES billings (id),
+  amount REAL NOT NULL,
+  date DATETIME NOT NULL,
+  created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_penalties_loan_id ON penalties (loan_id);
+
+-- migrate:down
+DROP TABLE penalties;
+DROP TABLE batch_runs;
@@ -0,0 +1,22 @@
+-- migrate:up
+-- A group (majelis) of borrowers jointly liable for each other's loans. A loan
+-- belongs to at most one group.
+CREATE TABLE loan_groups (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  branch TEXT NOT NULL DEFAULT '',
+  created_at DATETIME NOT NULL,
+  updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE loan_group_members (
+  loan_id TEXT PRIMARY KEY REFERENCES loans (id),
+  group_id TEXT NOT NULL REFERENCES loan_groups (id),
+  created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_loan_group_members_group_id ON loan_group_members (group_id, created_at);
+
+-- migrate:down
+DROP TABLE loan_group_members;
+DROP TABLE loan_groups;
@@ -0,0 +1,17 @@
+package scoring
+
+import (
+	"billing/internal/usecase"
+	"os"
+	"testing"
+)
+
+// TestMain configures the service the way the original API's clients expect:
+// their loan requests carry no product_code and no credentials, and name
+// customers that were never registered.
+func TestMain(m *testing.M) {
+	os.Setenv("BILLING_DEFAULT_PRODUCT_CODE", usecase.DefaultProductCode)
+	os.Setenv("BILLING_AUTH_DISABLED", "true")
+	os.Setenv("BILLING_REQUIRE_REGISTERED_CUSTOMER", "false")
+	os.Exit(m.Run())
+}
@@ -0,0 +1,255 @@
+package tests
+
+import (
+	"billing/internal/auth"
+	"billing/internal/config"
+	"billing/internal/model"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testViewerKey   = "viewer-key-0123456789"
+	testHS256Secret = "0123456789abcdef0123456789abcdef"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// authConfig enables API key, HS256 and RS256 authentication and returns the
+// RSA key that signs RS256 tokens.
+func authConfig(t *testing.T) (config.Config, *rsa.PrivateKey) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	assert.NoError(t, err)
+	pubFile := filepath.Join(t.TempDir(), "jwt.pub")
+	assert.NoError(t, os.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	cfg := testConfig()
+	cfg.Auth = auth.Config{
+		APIKeys: []auth.APIKeyConfig{
+			{Name: "reporting", KeySHA256: sha256Hex(testViewerKey), Roles: []string{auth.RoleViewer}},
+		},
+		JWT: auth.JWTConfig{
+			HS256Secret:        testHS256Secret,
+			RS256PublicKeyFile: pubFile,
+		},
+	}
+	assert.NoError(t, cfg.Validate())
+	return cfg, rsaKey
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, key any, subject string, roles []string, expiresIn time.Duration) string {
+	token := jwt.NewWithClaims(method, jwt.MapClaims{
+		"sub":   subject,
+		"roles": roles,
+		"exp":   time.Now().Add(expiresIn).Unix(),
+	})
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return signed
+}
+
+func bearer(token string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
+
+func paymentFor(loan model.LoanWithBills) model.MakePaymentRequest {
+	return model.MakePaymentRequest{
+		PaymentAmount: loan.Bills[0].Amount,
+		PaymentDate:   loan.Bills[0].DueDate,
+	}
+}
+
+// TestAuth_MissingCredentials tests protected routes reject anonymous callers with 401
+func TestAuth_MissingCredentials(t *testing.T) {
+	cfg, _ := authConfig(t)
+	rec := serve(newServerWith(cfg), http.MethodGet, "/bills/any-loan", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, "UNAUTHENTICATED", detail.Code)
+}
+
+// TestAuth_ProbesOpen tests health probes need no credentials
+func TestAuth_ProbesOpen(t *testing.T) {
+	cfg, _ := authConfig(t)
+	rec := serve(newServerWith(cfg), http.MethodGet, "/healthz", nil, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+// TestAuth_MetricsNeedMonitorRole tests GET /metrics rejects anonymous callers and callers without the monitor role
+func TestAuth_MetricsNeedMonitorRole(t *testing.T) {
+	cfg, _ := authConfig(t)
+	e := newServerWith(cfg)
+
+	rec := serve(e, http.MethodGet, "/metrics", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = serve(e, http.MethodGet, "/metrics", nil, http.Header{auth.HeaderAPIKey: {testViewerKey}})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	token := signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), "prometheus", []string{auth.RoleMonitor}, time.Hour)
+	rec = serve(e, http.MethodGet, "/metrics", nil, bearer(token))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "billing_")
+}
+
+// TestAuth_RequiredUnlessDisabled tests the API refuses to run without credentials unless authentication is explicitly disabled
+func TestAuth_RequiredUnlessDisabled(t *testing.T) {
+	_, err := auth.NewAuthenticator(auth.Config{})
+	assert.ErrorIs(t, err, auth.ErrNoCredentials)
+
+	authn, err := auth.NewAuthenticator(auth.Config{Disabled: true})
+	assert.NoError(t, err)
+	assert.False(t, authn.Enabled())
+
+	cfg, _ := authConfig(t)
+	cfg.Auth.Disabled = true
+	assert.ErrorContains(t, cfg.Validate(), "auth.disabled")
+
+	t.Setenv("BILLING_AUTH_DISABLED", "false")
+	cfg, err = config.Load()
+	assert.NoError(t, err)
+	_, err = auth.NewAuthenticator(cfg.Auth)
+	assert.ErrorIs(t, err, auth.ErrNoCredentials)
+}
+
+// TestAuth_ViewerAPIKey tests a viewer API key can read but not post payments
+func TestAuth_ViewerAPIKey(t *testing.T) {
+	loan, err := seedData()
+	if err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	cfg, _ := authConfig(t)
+	e := newServerWith(cfg)
+	header := http.Header{auth.HeaderAPIKey: {testViewerKey}}
+
+	rec := serve(e, http.MethodGet, "/bills/"+loan.Loan.ID, nil, header)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(e, http.MethodPost, "/bills/"+loan.Loan.ID+"/payments", paymentFor(loan), header)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, "FORBIDDEN", detail.Code)
+
+	rec = serve(e, http.MethodGet, "/bills/"+loan.Loan.ID, nil, http.Header{auth.HeaderAPIKey: {"wrong-key"}})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+// TestAuth_CollectorHS256 tests a collector token can post payments
+func TestAuth_CollectorHS256(t *testing.T) {
+	loan, err := seedData()
+	if err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	cfg, _ := authConfig(t)
+	token := signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), "collector-1", []string{auth.RoleCollector}, time.Hour)
+
+	rec := serve(newServerWith(cfg), http.MethodPost, "/bills/"+loan.Loan.ID+"/payments", paymentFor(loan), bearer(token))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+// TestAuth_OpsRS256 tests an ops token can create loans but not post payments
+func TestAuth_OpsRS256(t *testing.T) {
+	loan, err := seedData()
+	if err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	cfg, rsaKey := authConfig(t)
+	e := newServerWith(cfg)
+	token := signToken(t, jwt.SigningMethodRS256, rsaKey, "ops-1", []string{auth.RoleOps}, time.Hour)
+
+	rec := serve(e, http.MethodPost, "/bills", model.Loan{
+		CustomerID:   "cust-auth",
+		Name:         "Test Loan",
+		Period:       50,
+		Amount:       5000000,
+		InterestRate: 10,
+	}, bearer(token))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = serve(e, http.MethodPost, "/bills/"+loan.Loan.ID+"/payments", paymentFor(loan), bearer(token))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+// TestAuth_InvalidTokens tests expired, unsigned and wrongly signed tokens are rejected
+func TestAuth_InvalidTokens(t *testing.T) {
+	cfg, _ := authConfig(t)
+	e := newServerWith(cfg)
+	roles := []string{auth.RoleViewer}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	tokens := map[string]string{
+		"expired":      signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), "viewer-1", roles, -time.Minute),
+		"unsigned":     signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "viewer-1", roles, time.Hour),
+		"wrong secret": signToken(t, jwt.SigningMethodHS256, []byte("another-secret-another-secret-00"), "viewer-1", roles, time.Hour),
+		"wrong key":    signToken(t, jwt.SigningMethodRS256, otherKey, "viewer-1", roles, time.Hour),
+	}
+	for name, token := range tokens {
+		rec := serve(e, http.MethodGet, "/bills/any-loan", nil, bearer(token))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, name)
+	}
+}
+
+// TestAuth_BorrowerScope tests a borrower token reaches its own loans and gets 404 for anyone else's
+func TestAuth_BorrowerScope(t *testing.T) {
+	own, err := seedData()
+	if err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	other, err := seedData()
+	if err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	cfg, _ := authConfig(t)
+	e := newServerWith(cfg)
+	token := signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), own.Loan.CustomerID, []string{auth.RoleBorrower}, time.Hour)
+
+	for _, path := range []string{"/bills/" + own.Loan.ID, "/bills/" + own.Loan.ID + "/status"} {
+		rec := serve(e, http.MethodGet, path, nil, bearer(token))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+	}
+	rec := serve(e, http.MethodPost, "/bills/"+own.Loan.ID+"/payments", paymentFor(own), bearer(token))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	for _, rec := range []*httptest.ResponseRecorder{
+		serve(e, http.MethodGet, "/bills/"+other.Loan.ID, nil, bearer(token)),
+		serve(e, http.MethodGet, "/bills/"+other.Loan.ID+"/status", nil, bearer(token)),
+		serve(e, http.MethodPost, "/bills/"+other.Loan.ID+"/payments", paymentFor(other), bearer(token)),
+	} {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		detail, err := unmarshalResponse[errorDetail](rec)
+		assert.NoError(t, err)
+		assert.Equal(t, "LOAN_NOT_FOUND", detail.Code)
+	}
+
+	rec = serve(e, http.MethodPost, "/bills", model.Loan{
+		CustomerID:   own.Loan.CustomerID,
+		Name:         "Test Loan",
+		Period:       50,
+		Amount:       5000000,
+		InterestRate: 10,
+	}, bearer(token))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"billing/internal/batch"
+	"billing/internal/config"
+	"billing/internal/model"
+	"billing/internal/repository"
+	"billing/internal/usecase"
+	"billing/internal/util"
+	"billing/pkg/db"
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEOD_DelinquentLoanGetsPenalties tests the end-of-day run marks a loan with missed bills as delinquent and adds a penalty to each one
+func TestEOD_DelinquentLoanGetsPenalties(t *testing.T) {
+	database := requireDB(t)
+	loan, err := seedData()
+	if err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	resetTime := addTimeNow(3)
+	defer resetTime()
+
+	businessDate := util.GetCurrentTime()
+	database.Where("business_date = ?", businessDate.Format("2006-01-02")).Delete(&model.BatchRun{})
+
+	runner := batch.Runner{DB: database, ReportDir: t.TempDir(), PenaltyAmount: 5000}
+	run, err := runner.Run(context.Background(), businessDate)
+	assert.NoError(t, err)
+	assert.Equal(t, batch.RunStatusCompleted, run.Status)
+
+	var stored model.Loan
+	assert.NoError(t, database.Where("id = ?", loan.Loan.ID).First(&stored).Error)
+	assert.Equal(t, "DELINQUENT", stored.Status)
+	// Bills due on the business date count as missed, as in GetBillStatus
+	assert.InDelta(t, loan.Loan.Outstanding+3*5000, stored.Outstanding, 0.01)
+
+	var penalties int64
+	database.Model(&model.Penalty{}).Where("loan_id = ?", loan.Loan.ID).Count(&penalties)
+	assert.Equal(t, int64(3), penalties)
+
+	var bills []model.Billing
+	assert.NoError(t, database.Where("loan_id = ?", loan.Loan.ID).Order("sequence").Find(&bills).Error)
+	weekly := loan.Bills[0].Amount
+	assert.InDelta(t, weekly+5000, bills[0].Amount, 0.01)
+	assert.InDelta(t, weekly+5000, bills[2].Amount, 0.01)
+	assert.InDelta(t, weekly, bills[3].Amount, 0.01)
+
+	// A second run for the same date must not charge again
+	database.Where("business_date = ?", run.BusinessDate).Delete(&model.BatchRun{})
+	_, err = runner.Run(context.Background(), businessDate)
+	assert.NoError(t, err)
+	database.Model(&model.Penalty{}).Where("loan_id = ?", loan.Loan.ID).Count(&penalties)
+	assert.Equal(t, int64(3), penalties)
+	assert.NoError(t, database.Where("id = ?", loan.Loan.ID).First(&stored).Error)
+	assert.InDelta(t, loan.Loan.Outstanding+3*5000, stored.Outstanding, 0.01)
+
+	// The weekly amount alone no longer settles the penalised bill
+	req := mapAPI[APIMakePayment]
+	req.Param = map[string]string{"loan_id": loan.Loan.ID}
+	req.Body = model.MakePaymentRequest{PaymentAmount: weekly, PaymentDate: businessDate}
+	assert.Equal(t, http.StatusBadRequest, callAPI(req).Code)
+	req.Body = model.MakePaymentRequest{PaymentAmount: weekly + 5000, PaymentDate: businessDate}
+	assert.Equal(t, http.StatusOK, callAPI(req).Code)
+}
+
+// TestEOD_ResumeFromCheckpoint tests a run resumes after the last processed loan
+func TestEOD_ResumeFromCheckpoint(t *testing.T) {
+	database := requireDB(t)
+	loan, err := seedData()
+	if err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	resetTime := addTimeNow(3)
+	defer resetTime()
+
+	businessDate := util.GetCurrentTime().AddDate(0, 0, 1)
+	database.Where("business_date = ?", businessDate.Format("2006-01-02")).Delete(&model.BatchRun{})
+
+	runner := batch.Runner{DB: database, ReportDir: t.TempDir()}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	run, err := runner.Run(ctx, businessDate)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, batch.RunStatusRunning, run.Status)
+
+	// Pretend the crashed run got past every loan before the seeded one
+	database.Model(&model.BatchRun{}).Where("id = ?", run.ID).Update("last_loan_id", loan.Loan.ID)
+
+	run, err = runner.Run(context.Background(), businessDate)
+	assert.NoError(t, err)
+	assert.Equal(t, batch.RunStatusCompleted, run.Status)
+
+	var stored model.Loan
+	assert.NoError(t, database.Where("id = ?", loan.Loan.ID).First(&stored).Error)
+	assert.Equal(t, "IN_PROGRESS", stored.Status, "Loan before the checkpoint should not be processed again")
+}
+
+// TestEOD_SchedulerResumesUnfinishedRun tests the scheduler finishes a run left RUNNING by a crash as soon as it starts
+func TestEOD_SchedulerResumesUnfinishedRun(t *testing.T) {
+	cfg := config.Default()
+	cfg.DB.DSN = filepath.Join(t.TempDir(), "scheduler.db")
+	conn, err := db.Open(cfg.DB)
+	assert.NoError(t, err)
+	_, err = db.MigrateUp(conn)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	repos := repository.NewGormRepositories(conn)
+	product := usecase.DefaultProducts[0]
+	product.CreatedAt = time.Now()
+	assert.NoError(t, repos.Products.CreateIfMissing(ctx, &product))
+
+	businessDate := repository.StartOfDay(time.Now())
+	created := businessDate.AddDate(0, 0, -21)
+	loan := model.Loan{ID: "loan-scheduler", CustomerID: "cust-scheduler", Period: 2,
+		Amount: 1000000, InterestRate: 10, TotalAmount: 1100000, Outstanding: 1100000,
+		Status: model.LoanStatusInProgress, CreatedAt: created}
+	bills := []model.Billing{
+		{ID: "bill-scheduler-1", LoanID: loan.ID, Sequence: 1, Date: created, DueDate: created.AddDate(0, 0, 7), Amount: 550000, CreatedAt: created},
+		{ID: "bill-scheduler-2", LoanID: loan.ID, Sequence: 2, Date: created, DueDate: created.AddDate(0, 0, 14), Amount: 550000, CreatedAt: created},
+	}
+	assert.NoError(t, repos.Loans.CreateBatch(ctx, []model.ProductLoan{{Loan: loan, ProductCode: product.Code}}, bills))
+
+	assert.NoError(t, conn.Create(&model.BatchRun{
+		ID:           "run-scheduler",
+		BusinessDate: businessDate.Format(time.DateOnly),
+		Status:       batch.RunStatusRunning,
+		StartedAt:    time.Now(),
+	}).Error)
+
+	schedulerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	scheduler := batch.Scheduler{Runner: &batch.Runner{DB: conn, ReportDir: t.TempDir()}}
+	go scheduler.Start(schedulerCtx)
+
+	assert.Eventually(t, func() bool {
+		var run model.BatchRun
+		return conn.Where("id = ?", "run-scheduler").First(&run).Error == nil && run.Status == batch.RunStatusCompleted
+	}, 5*time.Second, 20*time.Millisecond)
+
+	var stored model.Loan
+	assert.NoError(t, conn.Where("id = ?", loan.ID).First(&stored).Error)
+	assert.Equal(t, model.LoanStatusDelinquent, stored.Status)
+}
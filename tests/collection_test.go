@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"billing/internal/auth"
+	"billing/internal/model"
+	"billing/internal/util"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func collectionsDue(t *testing.T, query url.Values) model.CollectionsDue {
+	rec := serve(newServer(), http.MethodGet, "/collections/due?"+query.Encode(), nil, nil)
+	if !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	due, err := unmarshalResponse[model.CollectionsDue](rec)
+	assert.NoError(t, err)
+	return due
+}
+
+func billSequences(loan model.CollectionLoan) []int {
+	seqs := make([]int, 0, len(loan.Bills))
+	for _, b := range loan.Bills {
+		seqs = append(seqs, b.Sequence)
+	}
+	return seqs
+}
+
+// TestCollectionsDue tests GET /collections/due lists a branch's bills due on a date, grouped by customer and loan.
+// It books loans far in the past so no other test's bills fall due on the same days.
+func TestCollectionsDue(t *testing.T) {
+	restore := addTimeNow(-1100)
+	start := util.GetCurrentTime()
+	customer := model.Customer{
+		ID:        fmt.Sprintf("cust-%d", randomNumber()),
+		Name:      "Field Customer",
+		Phone:     "+62811000001",
+		KYCStatus: model.KYCStatusVerified,
+		Branch:    fmt.Sprintf("BR-%d", randomNumber()),
+		CreatedAt: start,
+		UpdatedAt: start,
+	}
+	assert.NoError(t, backend.repos.Customers.Create(context.Background(), &customer))
+	loan := createLoanFor(t, customer.ID, 5000000)
+	unregistered := createLoanFor(t, fmt.Sprintf("cust-%d", randomNumber()), 5000000)
+	restore()
+
+	day := func(days int) string { return start.AddDate(0, 0, days).Format(time.DateOnly) }
+
+	due := collectionsDue(t, url.Values{"date": {day(7)}, "branch": {customer.Branch}})
+	assert.Equal(t, day(7), due.Date)
+	if assert.Len(t, due.Customers, 1) {
+		got := due.Customers[0]
+		assert.Equal(t, customer.ID, got.CustomerID)
+		assert.Equal(t, customer.Name, got.Name)
+		assert.Equal(t, customer.Branch, got.Branch)
+		if assert.Len(t, got.Loans, 1) {
+			assert.Equal(t, loan.ID, got.Loans[0].LoanID)
+			assert.Equal(t, []int{1}, billSequences(got.Loans[0]))
+			assert.InDelta(t, 110000, got.Loans[0].ExpectedAmount, 0.01)
+		}
+	}
+	assert.InDelta(t, 110000, due.TotalAmount, 0.01)
+
+	// Without a branch, customers with no record are listed too.
+	due = collectionsDue(t, url.Values{"date": {day(7)}})
+	var ids []string
+	for _, c := range due.Customers {
+		ids = append(ids, c.CustomerID)
+	}
+	assert.Contains(t, ids, customer.ID)
+	assert.Contains(t, ids, unregistered.CustomerID)
+
+	due = collectionsDue(t, url.Values{"date": {day(14)}, "branch": {customer.Branch}})
+	if assert.Len(t, due.Customers, 1) {
+		assert.Equal(t, []int{2}, billSequences(due.Customers[0].Loans[0]))
+	}
+
+	due = collectionsDue(t, url.Values{"date": {day(14)}, "branch": {customer.Branch}, "include_overdue": {"true"}})
+	if assert.Len(t, due.Customers, 1) {
+		assert.Equal(t, []int{1, 2}, billSequences(due.Customers[0].Loans[0]))
+		assert.InDelta(t, 220000, due.Customers[0].TotalAmount, 0.01)
+	}
+
+	// Once paid, a bill is no longer listed.
+	restore = addTimeNow(-1099)
+	rec := serve(newServer(), http.MethodPost, "/bills/"+loan.ID+"/payments", model.MakePaymentRequest{
+		PaymentAmount: 110000,
+		PaymentDate:   start.AddDate(0, 0, 7),
+	}, nil)
+	restore()
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	due = collectionsDue(t, url.Values{"date": {day(14)}, "branch": {customer.Branch}, "include_overdue": {"true"}})
+	if assert.Len(t, due.Customers, 1) {
+		assert.Equal(t, []int{2}, billSequences(due.Customers[0].Loans[0]))
+	}
+}
+
+// TestCollectionsDue_InvalidQuery tests GET /collections/due rejects malformed parameters
+func TestCollectionsDue_InvalidQuery(t *testing.T) {
+	rec := serve(newServer(), http.MethodGet, "/collections/due?date=tomorrow&include_overdue=maybe&branch=a%20b", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Len(t, detail.Fields, 3)
+}
+
+// TestCollectionsDue_Permissions tests borrowers cannot list collections
+func TestCollectionsDue_Permissions(t *testing.T) {
+	cfg, _ := authConfig(t)
+	e := newServerWith(cfg)
+
+	token := signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), "cust-1", []string{auth.RoleBorrower}, time.Hour)
+	rec := serve(e, http.MethodGet, "/collections/due", nil, bearer(token))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	token = signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), "officer-1", []string{auth.RoleCollector}, time.Hour)
+	rec = serve(e, http.MethodGet, "/collections/due", nil, bearer(token))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
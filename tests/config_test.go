@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"billing/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestConfig_EnvOverridesFile tests environment variables take precedence over the config file
+func TestConfig_EnvOverridesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{"server":{"port":9000},"eod":{"penalty_amount":5000}}`), 0o644)
+	assert.NoError(t, err)
+
+	t.Setenv(config.FileEnv, path)
+	t.Setenv("BILLING_PORT", "9100")
+
+	cfg, err := config.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, 9100, cfg.Server.Port)
+	assert.InDelta(t, 5000.0, cfg.EOD.PenaltyAmount, 0.01)
+	assert.Equal(t, config.Default().DB, cfg.DB)
+}
+
+// TestConfig_InvalidValues tests every invalid setting is reported at once
+func TestConfig_InvalidValues(t *testing.T) {
+	t.Setenv("BILLING_PORT", "70000")
+	t.Setenv("BILLING_DB_DRIVER", "mysql")
+	t.Setenv("BILLING_EOD_RUN_AT", "25:00")
+	t.Setenv("BILLING_LOG_LEVEL", "verbose")
+	t.Setenv("BILLING_AUTH_JWT_HS256_SECRET", "short")
+	t.Setenv("BILLING_MAX_ACTIVE_LOANS", "-1")
+
+	_, err := config.Load()
+	assert.ErrorContains(t, err, "server.port")
+	assert.ErrorContains(t, err, "db.driver")
+	assert.ErrorContains(t, err, "eod.run_at")
+	assert.ErrorContains(t, err, "log.level")
+	assert.ErrorContains(t, err, "auth.jwt.hs256_secret")
+	assert.ErrorContains(t, err, "loan.max_active_loans")
+}
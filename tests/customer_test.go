@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"billing/internal/auth"
+	"billing/internal/config"
+	"billing/internal/model"
+	"billing/internal/usecase"
+	"billing/internal/util"
+	"billing/internal/validation"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func seedCustomer(t *testing.T, kycStatus string) model.Customer {
+	customer := model.Customer{
+		// The SQLite database outlives the run, so a random number alone
+		// could name a customer that earlier loans already belong to.
+		ID:        "cust-" + uuid.NewString(),
+		Name:      "Test Customer",
+		Phone:     "+62811000000",
+		KYCStatus: kycStatus,
+		Branch:    "JKT-01",
+		CreatedAt: util.GetCurrentTime(),
+		UpdatedAt: util.GetCurrentTime(),
+	}
+	if err := backend.repos.Customers.Create(context.Background(), &customer); err != nil {
+		t.Fatalf("Failed to seed customer: %v", err)
+	}
+	return customer
+}
+
+func loanRequestFor(customerID string) model.ProductLoan {
+	return model.ProductLoan{Loan: model.Loan{
+		CustomerID:   customerID,
+		Name:         "Test Loan",
+		Period:       50,
+		Amount:       5000000,
+		InterestRate: 10,
+	}}
+}
+
+// TestGetCustomerLoans tests GET /customers/:customer_id/loans lists each loan with outstanding and delinquency
+func TestGetCustomerLoans(t *testing.T) {
+	customer := seedCustomer(t, model.KYCStatusVerified)
+	req := mapAPI[APICreatedBill]
+	req.Body = loanRequestFor(customer.ID)
+	for range 2 {
+		rec := callAPI(req)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	defer addTimeNow(3)()
+	rec := callAPI(APIRequest{Method: http.MethodGet, Path: "/customers/" + customer.ID + "/loans"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	respData, err := unmarshalResponse[model.CustomerLoans](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, customer.ID, respData.Customer.ID)
+	assert.Equal(t, model.KYCStatusVerified, respData.Customer.KYCStatus)
+	if assert.Len(t, respData.Loans, 2) {
+		for _, loan := range respData.Loans {
+			assert.Equal(t, customer.ID, loan.CustomerID)
+			assert.InDelta(t, 5500000.0, loan.Outstanding, 0.01)
+			assert.True(t, loan.IsDelinquent)
+			assert.NotNil(t, loan.DelinquentAt)
+		}
+	}
+}
+
+// TestGetCustomerLoans_NotFound tests an unknown customer returns 404
+func TestGetCustomerLoans_NotFound(t *testing.T) {
+	rec := callAPI(APIRequest{Method: http.MethodGet, Path: "/customers/missing-customer/loans"})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, "CUSTOMER_NOT_FOUND", detail.Code)
+}
+
+// TestGetCustomerLoans_BorrowerScope tests a borrower token only lists its own customer's loans
+func TestGetCustomerLoans_BorrowerScope(t *testing.T) {
+	own := seedCustomer(t, model.KYCStatusVerified)
+	other := seedCustomer(t, model.KYCStatusVerified)
+	cfg, _ := authConfig(t)
+	e := newServerWith(cfg)
+	token := signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), own.ID, []string{auth.RoleBorrower}, time.Hour)
+
+	rec := serve(e, http.MethodGet, "/customers/"+own.ID+"/loans", nil, bearer(token))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(e, http.MethodGet, "/customers/"+other.ID+"/loans", nil, bearer(token))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// TestCreateBills_CustomerNotEligible tests POST /bills rejects a customer whose KYC is not verified
+func TestCreateBills_CustomerNotEligible(t *testing.T) {
+	customer := seedCustomer(t, model.KYCStatusPending)
+	req := mapAPI[APICreatedBill]
+	req.Body = loanRequestFor(customer.ID)
+	rec := callAPI(req)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, "CUSTOMER_NOT_ELIGIBLE", detail.Code)
+}
+
+// TestCreateBills_RequireRegisteredCustomer tests unknown customers are rejected by default and accepted once registered
+func TestCreateBills_RequireRegisteredCustomer(t *testing.T) {
+	cfg := config.Default()
+	cfg.Loan.DefaultProductCode = usecase.DefaultProductCode
+	cfg.Auth.Disabled = true
+	e := newServerWith(cfg)
+
+	customerID := "cust-" + uuid.NewString()
+	rec := serve(e, http.MethodPost, "/bills", loanRequestFor(customerID), nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, validation.Errors{{
+		Field:   "customer_id",
+		Code:    validation.CodeUnknownReference,
+		Message: fmt.Sprintf("unknown customer %q", customerID),
+	}}, detail.Fields)
+
+	rec = serve(e, http.MethodPost, "/customers", model.CreateCustomerRequest{ID: customerID, Name: "Test Customer", KYCStatus: model.KYCStatusVerified}, nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = serve(e, http.MethodPost, "/bills", loanRequestFor(customerID), nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+// TestCreateCustomer tests a customer is registered with a pending KYC status by default
+func TestCreateCustomer(t *testing.T) {
+	e := newServer()
+	customerID := "cust-" + uuid.NewString()
+
+	rec := serve(e, http.MethodPost, "/customers", model.CreateCustomerRequest{ID: customerID, Name: " Siti ", Phone: "+62811000000", Branch: "JKT-01"}, nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	created, err := unmarshalResponse[model.Customer](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, customerID, created.ID)
+	assert.Equal(t, "Siti", created.Name)
+	assert.Equal(t, model.KYCStatusPending, created.KYCStatus)
+
+	stored, err := backend.repos.Customers.GetByID(context.Background(), customerID)
+	assert.NoError(t, err)
+	assert.Equal(t, "JKT-01", stored.Branch)
+}
+
+// TestCreateCustomer_Duplicate tests registering a taken ID answers 409 and keeps the existing customer
+func TestCreateCustomer_Duplicate(t *testing.T) {
+	customer := seedCustomer(t, model.KYCStatusVerified)
+
+	rec := serve(newServer(), http.MethodPost, "/customers", model.CreateCustomerRequest{ID: customer.ID, Name: "Someone Else"}, nil)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, "CUSTOMER_EXISTS", detail.Code)
+
+	stored, err := backend.repos.Customers.GetByID(context.Background(), customer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, customer.Name, stored.Name)
+}
+
+// TestCreateCustomer_Invalid tests every invalid field of a registration is reported
+func TestCreateCustomer_Invalid(t *testing.T) {
+	rec := serve(newServer(), http.MethodPost, "/customers", model.CreateCustomerRequest{ID: "cust 1", KYCStatus: "DONE"}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	var fields []string
+	for _, f := range detail.Fields {
+		fields = append(fields, f.Field)
+	}
+	assert.Equal(t, []string{"id", "name", "kyc_status"}, fields)
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"billing/internal/model"
+	"billing/internal/repository"
+	"billing/internal/usecase"
+	"billing/internal/validation"
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func reasonCodes(fields validation.Errors) []string {
+	codes := make([]string, 0, len(fields))
+	for _, f := range fields {
+		codes = append(codes, f.Code)
+	}
+	return codes
+}
+
+// TestCreateBills_ActiveLoanLimit tests POST /bills rejects a customer already at the active loan limit
+func TestCreateBills_ActiveLoanLimit(t *testing.T) {
+	customer := seedCustomer(t, model.KYCStatusVerified)
+	cfg := testConfig()
+	cfg.Loan.MaxActiveLoans = 1
+	e := newServerWith(cfg)
+
+	rec := serve(e, http.MethodPost, "/bills", loanRequestFor(customer.ID), nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = serve(e, http.MethodPost, "/bills", loanRequestFor(customer.ID), nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, "CUSTOMER_NOT_ELIGIBLE", detail.Code)
+	assert.Equal(t, []string{usecase.ReasonActiveLoanLimit}, reasonCodes(detail.Fields))
+}
+
+// slowLoans returns a customer's loans slowly, widening the window between
+// the eligibility check reading them and the insert.
+type slowLoans struct {
+	repository.LoanRepository
+}
+
+func (r slowLoans) ListByCustomerID(ctx context.Context, customerID string) ([]model.Loan, error) {
+	loans, err := r.LoanRepository.ListByCustomerID(ctx, customerID)
+	time.Sleep(20 * time.Millisecond)
+	return loans, err
+}
+
+// TestCreateBills_ConcurrentActiveLoanLimit tests concurrent loans for one customer cannot all pass the active loan limit
+func TestCreateBills_ConcurrentActiveLoanLimit(t *testing.T) {
+	customer := seedCustomer(t, model.KYCStatusVerified)
+	products := usecase.ProductUsecase{Products: backend.repos.Products}
+	assert.NoError(t, products.SeedDefaults(context.Background()))
+	loanUsecase := usecase.LoanUsecase{
+		Loans:              slowLoans{backend.repos.Loans},
+		Billings:           backend.repos.Billings,
+		Products:           backend.repos.Products,
+		Customers:          backend.repos.Customers,
+		DefaultProductCode: usecase.DefaultProductCode,
+		Eligibility:        usecase.EligibilityRules{MaxActiveLoans: 1},
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = loanUsecase.CreateBills(context.Background(), loanRequestFor(customer.ID))
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		assert.ErrorIs(t, err, usecase.ErrCustomerNotEligible)
+	}
+	assert.Equal(t, 1, created)
+
+	loans, err := backend.repos.Loans.ListByCustomerID(context.Background(), customer.ID)
+	assert.NoError(t, err)
+	assert.Len(t, loans, 1)
+}
+
+// TestCreateBills_ExposureLimit tests POST /bills rejects a loan that would push outstanding over the limit
+func TestCreateBills_ExposureLimit(t *testing.T) {
+	customer := seedCustomer(t, model.KYCStatusVerified)
+	cfg := testConfig()
+	cfg.Loan.MaxExposure = 10000000
+	e := newServerWith(cfg)
+
+	// 5,500,000 outstanding after the first loan; a second would reach 11,000,000.
+	rec := serve(e, http.MethodPost, "/bills", loanRequestFor(customer.ID), nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = serve(e, http.MethodPost, "/bills", loanRequestFor(customer.ID), nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	if assert.Len(t, detail.Fields, 1) {
+		assert.Equal(t, "amount", detail.Fields[0].Field)
+		assert.Equal(t, usecase.ReasonExposureLimit, detail.Fields[0].Code)
+	}
+}
+
+// TestCreateBills_DelinquentCustomer tests POST /bills is refused while the customer has a delinquent loan
+func TestCreateBills_DelinquentCustomer(t *testing.T) {
+	customer := seedCustomer(t, model.KYCStatusVerified)
+	e := newServerWith(testConfig())
+
+	rec := serve(e, http.MethodPost, "/bills", loanRequestFor(customer.ID), nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	defer addTimeNow(3)()
+	rec = serve(e, http.MethodPost, "/bills", loanRequestFor(customer.ID), nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{usecase.ReasonDelinquentLoan}, reasonCodes(detail.Fields))
+
+	cfg := testConfig()
+	cfg.Loan.BlockWhenDelinquent = false
+	rec = serve(newServerWith(cfg), http.MethodPost, "/bills", loanRequestFor(customer.ID), nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+// TestCreateBills_AllReasons tests every broken rule is reported in one response
+func TestCreateBills_AllReasons(t *testing.T) {
+	customer := seedCustomer(t, model.KYCStatusVerified)
+	rec := serve(newServerWith(testConfig()), http.MethodPost, "/bills", loanRequestFor(customer.ID), nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	cfg := testConfig()
+	cfg.Loan.MaxActiveLoans = 1
+	cfg.Loan.MaxExposure = 6000000
+	defer addTimeNow(3)()
+	rec = serve(newServerWith(cfg), http.MethodPost, "/bills", loanRequestFor(customer.ID), nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{usecase.ReasonDelinquentLoan, usecase.ReasonActiveLoanLimit, usecase.ReasonExposureLimit}, reasonCodes(detail.Fields))
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"billing/internal/auth"
+	"billing/internal/export"
+	"billing/internal/model"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+// createPaidLoan books a loan and pays its first bill.
+func createPaidLoan(t *testing.T, customerID string) model.Loan {
+	defer addTimeNow(0)()
+	loan := createLoanFor(t, customerID, 5000000)
+
+	defer addTimeNow(1)()
+	rec := serve(newServer(), http.MethodPost, "/bills/"+loan.ID+"/payments", model.MakePaymentRequest{
+		PaymentAmount: 110000,
+		PaymentDate:   loan.CreatedAt.AddDate(0, 0, 7),
+	}, nil)
+	if !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	return loan
+}
+
+func readCSV(t *testing.T, body []byte) [][]string {
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return records
+}
+
+// TestGetBills_CSV tests GET /bills/:loan_id returns the schedule as CSV when the client accepts text/csv
+func TestGetBills_CSV(t *testing.T) {
+	loan := createPaidLoan(t, fmt.Sprintf("cust-%d", randomNumber()))
+
+	rec := serve(newServer(), http.MethodGet, "/bills/"+loan.ID, nil, http.Header{"Accept": {"text/csv"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, export.MIMECSV, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "attachment")
+
+	records := readCSV(t, rec.Body.Bytes())
+	if assert.Len(t, records, 51) {
+		assert.Equal(t, []string{"loan_id", "sequence", "due_date", "amount", "status", "payment_date"}, records[0])
+		assert.Equal(t, []string{loan.ID, "1"}, records[1][:2])
+		assert.Equal(t, "110000.00", records[1][3])
+		assert.Equal(t, export.BillPaid, records[1][4])
+		assert.NotEmpty(t, records[1][5])
+		assert.Equal(t, export.BillUnpaid, records[2][4])
+		assert.Empty(t, records[2][5])
+	}
+}
+
+// TestGetBills_XLSX tests GET /bills/:loan_id?format=xlsx returns a workbook with the loan terms and its bills
+func TestGetBills_XLSX(t *testing.T) {
+	loan := createPaidLoan(t, fmt.Sprintf("cust-%d", randomNumber()))
+
+	rec := serve(newServer(), http.MethodGet, "/bills/"+loan.ID+"?format=xlsx", nil, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, export.MIMEXLSX, rec.Header().Get("Content-Type"))
+
+	f, err := excelize.OpenReader(bytes.NewReader(rec.Body.Bytes()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows("Schedule")
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 59) {
+		assert.Equal(t, []string{"Loan", loan.ID}, rows[0])
+		assert.Equal(t, "Sequence", rows[8][0])
+		assert.Equal(t, "1", rows[9][0])
+		assert.Equal(t, export.BillPaid, rows[9][3])
+		assert.Equal(t, export.BillUnpaid, rows[10][3])
+	}
+	amount, err := f.GetCellValue("Schedule", "C10", excelize.Options{RawCellValue: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "110000", amount)
+}
+
+// TestGetBills_UnknownFormat tests GET /bills/:loan_id rejects an unsupported format
+func TestGetBills_UnknownFormat(t *testing.T) {
+	rec := serve(newServer(), http.MethodGet, "/bills/any-loan?format=doc", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	if assert.Len(t, detail.Fields, 1) {
+		assert.Equal(t, "format", detail.Fields[0].Field)
+	}
+}
+
+// TestExportLoans tests GET /reports/loans.csv streams every loan matching the search filters
+func TestExportLoans(t *testing.T) {
+	customerID := fmt.Sprintf("cust-%d", randomNumber())
+	paid := createPaidLoan(t, customerID)
+	unpaid := createLoanFor(t, customerID, 2000000)
+
+	rec := serve(newServer(), http.MethodGet, "/reports/loans.csv?sort=amount&customer_id="+customerID, nil, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, export.MIMECSV, rec.Header().Get("Content-Type"))
+
+	records := readCSV(t, rec.Body.Bytes())
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, "id", records[0][0])
+		assert.Equal(t, unpaid.ID, records[1][0])
+		assert.Equal(t, paid.ID, records[2][0])
+		assert.Equal(t, "5390000.00", records[2][8])
+		assert.Equal(t, model.LoanStatusInProgress, records[2][9])
+	}
+
+	rec = serve(newServer(), http.MethodGet, "/reports/loans.csv?sort=name", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// TestExportLoans_Permissions tests borrowers cannot export the loan book
+func TestExportLoans_Permissions(t *testing.T) {
+	cfg, _ := authConfig(t)
+	token := signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), "cust-1", []string{auth.RoleBorrower}, time.Hour)
+
+	rec := serve(newServerWith(cfg), http.MethodGet, "/reports/loans.csv", nil, bearer(token))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+// TestExportLoans_FormulaInjection tests GET /reports/loans.csv writes text cells that a spreadsheet would run as a formula with a leading apostrophe
+func TestExportLoans_FormulaInjection(t *testing.T) {
+	customerID := fmt.Sprintf("-cust-%d", randomNumber())
+	loan := loanRequestFor(customerID)
+	loan.Name = `=HYPERLINK("http://evil.example","click")`
+	rec := serve(newServer(), http.MethodPost, "/bills", loan, nil)
+	if !assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+
+	rec = serve(newServer(), http.MethodGet, "/reports/loans.csv?customer_id="+customerID, nil, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	records := readCSV(t, rec.Body.Bytes())
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "'"+customerID, records[1][1])
+		assert.Equal(t, `'=HYPERLINK("http://evil.example","click")`, records[1][3])
+		assert.Equal(t, "5000000.00", records[1][5])
+		assert.Equal(t, model.LoanStatusInProgress, records[1][9])
+	}
+}
+
+// TestWritePaymentImportCSV_FormulaInjection tests the payment import report neutralises references that start a formula
+func TestWritePaymentImportCSV_FormulaInjection(t *testing.T) {
+	var buf bytes.Buffer
+	err := export.WritePaymentImportCSV(&buf, model.PaymentImportReport{Results: []model.PaymentImportResult{
+		{Line: 2, Reference: "@SUM(A1:A9)", LoanID: "loan-1", Amount: 110000, Status: "rejected", Code: "NOT_FOUND", Message: "+1 unknown"},
+		{Line: 3, Reference: "\tref", LoanID: "loan-2", Amount: 110000, Status: "rejected"},
+	}})
+	assert.NoError(t, err)
+
+	records := readCSV(t, buf.Bytes())
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, "'@SUM(A1:A9)", records[1][1])
+		assert.Equal(t, "loan-1", records[1][2])
+		assert.Equal(t, "110000.00", records[1][3])
+		assert.Equal(t, "'+1 unknown", records[1][7])
+		assert.Equal(t, "'\tref", records[2][1])
+	}
+}
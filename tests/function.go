@@ -3,16 +3,24 @@ package tests
 import (
 	"billing/api"
 	"billing/api/response"
+	"billing/internal/config"
 	"billing/internal/model"
+	"billing/internal/repository"
+	"billing/internal/usecase"
 	"billing/internal/util"
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"testing"
 	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 )
 
 type APIRequest struct {
@@ -48,9 +56,79 @@ var mapAPI = map[int]APIRequest{
 	},
 }
 
+const (
+	BackendSQLite   = "sqlite"
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+// testBackend is the storage the suite runs against; TestMain runs every test
+// once per backend. db is nil for backends without a SQL database.
+type testBackend struct {
+	name  string
+	repos repository.Repositories
+	db    *gorm.DB
+}
+
+var backend testBackend
+
+func newServer() *echo.Echo {
+	if backend.name == BackendSQLite {
+		return api.Init()
+	}
+	return api.New(testConfig(), backend.repos)
+}
+
+// testConfig is the default configuration with the settings TestMain also
+// sets through the environment for api.Init: loans that name no product get
+// the seeded default one and requests need no credentials, as the original
+// API's clients expect.
+func testConfig() config.Config {
+	cfg := config.Default()
+	cfg.Loan.DefaultProductCode = usecase.DefaultProductCode
+	cfg.Auth.Disabled = true
+	cfg.Loan.RequireRegisteredCustomer = false
+	return cfg
+}
+
+// newServerWith builds a server on the current backend with cfg instead of
+// the environment's configuration.
+func newServerWith(cfg config.Config) *echo.Echo {
+	return api.New(cfg, backend.repos)
+}
+
+// serve sends one JSON request to e with the given extra headers.
+func serve(e *echo.Echo, method, path string, body any, header http.Header) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		jsonBody, _ := json.Marshal(body)
+		reader = bytes.NewReader(jsonBody)
+	}
+
+	httpReq := httptest.NewRequest(method, path, reader)
+	httpReq.Header.Set("Content-Type", "application/json")
+	for k, values := range header {
+		for _, v := range values {
+			httpReq.Header.Add(k, v)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httpReq)
+	return rec
+}
+
+// requireDB skips tests that need to reach into a SQL database.
+func requireDB(t *testing.T) *gorm.DB {
+	if backend.db == nil {
+		t.Skipf("test requires a SQL backend, running on %s", backend.name)
+	}
+	return backend.db
+}
+
 func callAPI(req APIRequest) *httptest.ResponseRecorder {
 	// Setup
-	e := api.Init()
+	e := newServer()
 
 	jsonBody, _ := json.Marshal(req.Body)
 
@@ -0,0 +1,269 @@
+package tests
+
+import (
+	"billing/internal/auth"
+	"billing/internal/model"
+	"billing/internal/repository"
+	"billing/internal/usecase"
+	"billing/internal/util"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// createGroupLoans books n loans three weeks ago, so each has three bills due today.
+func createGroupLoans(t *testing.T, n int) []model.Loan {
+	defer addTimeNow(-3)()
+	loans := make([]model.Loan, 0, n)
+	for range n {
+		loans = append(loans, createLoanFor(t, fmt.Sprintf("cust-%d", randomNumber()), 5000000))
+	}
+	return loans
+}
+
+func groupLoanIDs(loans []model.Loan) []string {
+	ids := make([]string, 0, len(loans))
+	for _, loan := range loans {
+		ids = append(ids, loan.ID)
+	}
+	return ids
+}
+
+func createGroup(t *testing.T, loans []model.Loan) model.LoanGroupView {
+	rec := serve(newServer(), http.MethodPost, "/groups", model.CreateGroupRequest{
+		Name:    "Majelis Melati",
+		Branch:  "BR-01",
+		LoanIDs: groupLoanIDs(loans),
+	}, nil)
+	if !assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	view, err := unmarshalResponse[model.LoanGroupView](rec)
+	assert.NoError(t, err)
+	return view
+}
+
+func getGroup(t *testing.T, groupID string) model.LoanGroupView {
+	rec := serve(newServer(), http.MethodGet, "/groups/"+groupID, nil, nil)
+	if !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	view, err := unmarshalResponse[model.LoanGroupView](rec)
+	assert.NoError(t, err)
+	return view
+}
+
+// TestCreateGroup tests POST /groups links loans and GET /groups/:group_id combines their outstanding and delinquency
+func TestCreateGroup(t *testing.T) {
+	loans := createGroupLoans(t, 3)
+
+	created := createGroup(t, loans)
+	assert.NotEmpty(t, created.Group.ID)
+	assert.Equal(t, "Majelis Melati", created.Group.Name)
+
+	view := getGroup(t, created.Group.ID)
+	assert.Equal(t, groupLoanIDs(loans), []string{view.Loans[0].ID, view.Loans[1].ID, view.Loans[2].ID})
+	assert.Equal(t, 3*5500000.0, view.TotalOutstanding)
+	assert.Equal(t, 3*330000.0, view.DueAmount)
+	assert.Equal(t, 330000.0, view.Loans[0].DueAmount)
+	assert.Equal(t, 3, view.DelinquentLoans)
+	assert.True(t, view.IsDelinquent)
+	assert.True(t, view.Loans[0].IsDelinquent)
+}
+
+// TestCreateGroup_InvalidLoans tests POST /groups rejects unknown loans with 400 and loans already in a group with 409
+func TestCreateGroup_InvalidLoans(t *testing.T) {
+	loans := createGroupLoans(t, 1)
+	createGroup(t, loans)
+
+	rec := serve(newServer(), http.MethodPost, "/groups", model.CreateGroupRequest{Name: "Again", LoanIDs: groupLoanIDs(loans)}, nil)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), usecase.ErrLoanAlreadyGrouped.Code)
+
+	rec = serve(newServer(), http.MethodPost, "/groups", model.CreateGroupRequest{Name: "Unknown", LoanIDs: []string{"no-such-loan"}}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(newServer(), http.MethodPost, "/groups", model.CreateGroupRequest{LoanIDs: []string{}}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(newServer(), http.MethodGet, "/groups/no-such-group", nil, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// staleGroups finds no loan in a group, as if another request grouped them
+// after the check.
+type staleGroups struct {
+	repository.GroupRepository
+}
+
+func (staleGroups) GroupOf(context.Context, string) (string, error) {
+	return "", repository.ErrNotFound
+}
+
+// TestCreateGroup_Race tests a loan grouped by a concurrent request after the check is refused with 409 and nothing is stored
+func TestCreateGroup_Race(t *testing.T) {
+	loans := createGroupLoans(t, 2)
+	group := createGroup(t, loans[:1])
+
+	loanUsecase := usecase.LoanUsecase{Loans: backend.repos.Loans, Billings: backend.repos.Billings, Groups: staleGroups{backend.repos.Groups}}
+	_, err := loanUsecase.CreateGroup(context.Background(), model.CreateGroupRequest{Name: "Again", LoanIDs: groupLoanIDs(loans)})
+	assert.ErrorIs(t, err, usecase.ErrLoanAlreadyGrouped)
+
+	_, err = loanUsecase.AddGroupLoans(context.Background(), group.Group.ID, groupLoanIDs(loans))
+	assert.ErrorIs(t, err, usecase.ErrLoanAlreadyGrouped)
+
+	_, err = backend.repos.Groups.GroupOf(context.Background(), loans[1].ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.Len(t, getGroup(t, group.Group.ID).Loans, 1)
+}
+
+// TestAddGroupLoans tests POST /groups/:group_id/loans adds loans after the existing members
+func TestAddGroupLoans(t *testing.T) {
+	loans := createGroupLoans(t, 3)
+	group := createGroup(t, loans[:1])
+
+	rec := serve(newServer(), http.MethodPost, "/groups/"+group.Group.ID+"/loans", model.AddGroupLoansRequest{LoanIDs: groupLoanIDs(loans[1:])}, nil)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	view := getGroup(t, group.Group.ID)
+	if assert.Len(t, view.Loans, 3) {
+		assert.Equal(t, groupLoanIDs(loans), []string{view.Loans[0].ID, view.Loans[1].ID, view.Loans[2].ID})
+	}
+}
+
+// TestMakeGroupPayment tests POST /groups/:group_id/payments pays the oldest due bills of every member from one lump sum
+func TestMakeGroupPayment(t *testing.T) {
+	loans := createGroupLoans(t, 3)
+	group := createGroup(t, loans)
+	path := "/groups/" + group.Group.ID + "/payments"
+	today := util.GetCurrentTime()
+
+	rec := serve(newServer(), http.MethodPost, path, model.GroupPaymentRequest{PaymentAmount: 4 * 110000, PaymentDate: today}, nil)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	payment, err := unmarshalResponse[model.GroupPayment](rec)
+	assert.NoError(t, err)
+	if assert.Len(t, payment.Allocations, 4) {
+		for i, a := range payment.Allocations[:3] {
+			assert.Equal(t, loans[i].ID, a.LoanID)
+			assert.Equal(t, 1, a.Sequence)
+		}
+		assert.Equal(t, loans[0].ID, payment.Allocations[3].LoanID)
+		assert.Equal(t, 2, payment.Allocations[3].Sequence)
+	}
+
+	view := getGroup(t, group.Group.ID)
+	assert.Equal(t, 3*5500000.0-4*110000, view.TotalOutstanding)
+	assert.Equal(t, 5*110000.0, view.DueAmount)
+	assert.Equal(t, 110000.0, view.Loans[0].DueAmount)
+	assert.Equal(t, 2, view.DelinquentLoans)
+
+	rec = serve(newServer(), http.MethodPost, path, model.GroupPaymentRequest{PaymentAmount: 150000, PaymentDate: today}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), usecase.ErrGroupAmountMismatch.Code)
+
+	rec = serve(newServer(), http.MethodPost, path, model.GroupPaymentRequest{PaymentAmount: 6 * 110000, PaymentDate: today}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, 5*110000.0, getGroup(t, group.Group.ID).DueAmount)
+
+	rec = serve(newServer(), http.MethodPost, path, model.GroupPaymentRequest{PaymentAmount: 0, PaymentDate: today}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// TestMakeGroupPayment_NoPendingBill tests a group with nothing due refuses a payment
+func TestMakeGroupPayment_NoPendingBill(t *testing.T) {
+	loan := createLoanFor(t, fmt.Sprintf("cust-%d", randomNumber()), 5000000)
+	group := createGroup(t, []model.Loan{loan})
+
+	rec := serve(newServer(), http.MethodPost, "/groups/"+group.Group.ID+"/payments", model.GroupPaymentRequest{
+		PaymentAmount: 110000,
+		PaymentDate:   util.GetCurrentTime(),
+	}, nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), usecase.ErrNoPendingBill.Code)
+}
+
+// TestGroup_BorrowerScope tests borrowers see only their own groups and cannot post group payments
+func TestGroup_BorrowerScope(t *testing.T) {
+	loans := createGroupLoans(t, 2)
+	group := createGroup(t, loans)
+	cfg, _ := authConfig(t)
+	e := newServerWith(cfg)
+	path := "/groups/" + group.Group.ID
+
+	member := signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), loans[1].CustomerID, []string{auth.RoleBorrower}, time.Hour)
+	rec := serve(e, http.MethodGet, path, nil, bearer(member))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	view, err := unmarshalResponse[model.LoanGroupView](rec)
+	assert.NoError(t, err)
+	if assert.Len(t, view.Loans, 1, "a borrower sees only their own loans in the group") {
+		assert.Equal(t, loans[1].ID, view.Loans[0].ID)
+		assert.Equal(t, view.Loans[0].Outstanding, view.TotalOutstanding)
+	}
+
+	rec = serve(e, http.MethodPost, path+"/payments", model.GroupPaymentRequest{PaymentAmount: 220000, PaymentDate: util.GetCurrentTime()}, bearer(member))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	outsider := signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), fmt.Sprintf("cust-%d", randomNumber()), []string{auth.RoleBorrower}, time.Hour)
+	rec = serve(e, http.MethodGet, path, nil, bearer(outsider))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	collector := signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), "officer-1", []string{auth.RoleCollector}, time.Hour)
+	rec = serve(e, http.MethodPost, path+"/payments", model.GroupPaymentRequest{PaymentAmount: 220000, PaymentDate: util.GetCurrentTime()}, bearer(collector))
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+// TestMakeGroupPayment_NoSkipping tests a sum that only covers a later bill is refused rather than skipping the bill due first
+func TestMakeGroupPayment_NoSkipping(t *testing.T) {
+	restore := addTimeNow(-1)
+	loans := []model.Loan{
+		createLoanFor(t, fmt.Sprintf("cust-%d", randomNumber()), 10000000),
+		createLoanFor(t, fmt.Sprintf("cust-%d", randomNumber()), 5000000),
+	}
+	restore()
+	group := createGroup(t, loans)
+	path := "/groups/" + group.Group.ID + "/payments"
+
+	rec := serve(newServer(), http.MethodPost, path, model.GroupPaymentRequest{PaymentAmount: 110000, PaymentDate: util.GetCurrentTime()}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), usecase.ErrGroupAmountMismatch.Code)
+	assert.Equal(t, 330000.0, getGroup(t, group.Group.ID).DueAmount)
+
+	rec = serve(newServer(), http.MethodPost, path, model.GroupPaymentRequest{PaymentAmount: 330000, PaymentDate: util.GetCurrentTime()}, nil)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, 0.0, getGroup(t, group.Group.ID).DueAmount)
+}
+
+// recordingLoans records the payments posted through it, then fails.
+type recordingLoans struct {
+	repository.LoanRepository
+	calls [][]repository.BillPayment
+}
+
+func (r *recordingLoans) ApplyPayments(_ context.Context, payments []repository.BillPayment) error {
+	r.calls = append(r.calls, payments)
+	return errors.New("connection reset by peer")
+}
+
+// TestMakeGroupPayment_SingleTransaction tests every bill of a group payment is posted in one call to storage, so a failure pays nothing
+func TestMakeGroupPayment_SingleTransaction(t *testing.T) {
+	loans := createGroupLoans(t, 2)
+	group := createGroup(t, loans)
+
+	store := &recordingLoans{LoanRepository: backend.repos.Loans}
+	loanUsecase := usecase.LoanUsecase{Loans: store, Billings: backend.repos.Billings, Groups: backend.repos.Groups}
+	_, err := loanUsecase.MakeGroupPayment(context.Background(), group.Group.ID, model.GroupPaymentRequest{
+		PaymentAmount: 4 * 110000,
+		PaymentDate:   util.GetCurrentTime(),
+	})
+	assert.ErrorContains(t, err, "connection reset by peer")
+	if assert.Len(t, store.calls, 1) {
+		assert.Len(t, store.calls[0], 4)
+	}
+	assert.Equal(t, 6*110000.0, getGroup(t, group.Group.ID).DueAmount)
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"billing/api/handler"
+	"billing/internal/buildinfo"
+	"billing/internal/logging"
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHealthz tests GET /healthz answers without checking dependencies
+func TestHealthz(t *testing.T) {
+	rec := callAPI(APIRequest{Method: http.MethodGet, Path: "/healthz"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+// TestReadyz tests GET /readyz reports ready on a migrated database
+func TestReadyz(t *testing.T) {
+	rec := callAPI(APIRequest{Method: http.MethodGet, Path: "/readyz"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	respData, err := unmarshalResponse[map[string]string](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, "ready", respData["status"])
+}
+
+type failingHealth struct{ err error }
+
+func (h failingHealth) Ready(context.Context) error {
+	return h.err
+}
+
+// TestReadyz_NotReady tests GET /readyz answers 503 with a generic message and logs the cause
+func TestReadyz_NotReady(t *testing.T) {
+	var logs bytes.Buffer
+	health := handler.HealthHandler{
+		Health: failingHealth{err: errors.New("schema at version 20261019000003, want 20261019000008")},
+		Logger: logging.New(&logs, slog.LevelInfo),
+	}
+	e := echo.New()
+	e.GET("/readyz", health.Readyz)
+
+	rec := serve(e, http.MethodGet, "/readyz", nil, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "schema")
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, "NOT_READY", detail.Code)
+	assert.Contains(t, logs.String(), "schema at version")
+}
+
+// TestVersion tests GET /version returns the build metadata
+func TestVersion(t *testing.T) {
+	rec := callAPI(APIRequest{Method: http.MethodGet, Path: "/version"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	respData, err := unmarshalResponse[buildinfo.Info](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, buildinfo.Version, respData.Version)
+	assert.NotEmpty(t, respData.GoVersion)
+}
@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"billing/internal/auth"
+	"billing/internal/model"
+	"billing/internal/usecase"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func batchReport(t *testing.T, rec *httptest.ResponseRecorder) model.LoanBatchReport {
+	if !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	report, err := unmarshalResponse[model.LoanBatchReport](rec)
+	assert.NoError(t, err)
+	return report
+}
+
+func batchStatuses(report model.LoanBatchReport) []string {
+	statuses := make([]string, 0, len(report.Results))
+	for _, r := range report.Results {
+		statuses = append(statuses, r.Status+" "+r.Code)
+	}
+	return statuses
+}
+
+// TestCreateLoansBatch_JSON tests POST /loans/batch creates the valid loans of a JSON array and reports the rest
+func TestCreateLoansBatch_JSON(t *testing.T) {
+	noPeriod := loanRequestFor(fmt.Sprintf("cust-%d", randomNumber()))
+	noPeriod.Period = 0
+	unknownProduct := loanRequestFor(fmt.Sprintf("cust-%d", randomNumber()))
+	unknownProduct.ProductCode = "NO-SUCH-PRODUCT"
+	loans := []model.ProductLoan{
+		loanRequestFor(fmt.Sprintf("cust-%d", randomNumber())),
+		noPeriod,
+		loanRequestFor(fmt.Sprintf("cust-%d", randomNumber())),
+		unknownProduct,
+	}
+
+	report := batchReport(t, serve(newServer(), http.MethodPost, "/loans/batch", loans, nil))
+	assert.Equal(t, 4, report.Rows)
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 2, report.Rejected)
+	assert.Equal(t, []string{
+		model.BatchLoanCreated + " ",
+		model.BatchLoanRejected + " VALIDATION_FAILED",
+		model.BatchLoanCreated + " ",
+		model.BatchLoanRejected + " VALIDATION_FAILED",
+	}, batchStatuses(report))
+	assert.Equal(t, 2, report.Results[1].Row)
+	assert.Contains(t, report.Results[1].Message, "period")
+	assert.Contains(t, report.Results[3].Message, "product_code")
+
+	created := report.Results[2]
+	assert.Equal(t, loans[2].CustomerID, created.CustomerID)
+	rec := serve(newServer(), http.MethodGet, "/bills/"+created.LoanID, nil, nil)
+	got, err := unmarshalResponse[model.LoanWithBills](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, loans[2].CustomerID, got.Loan.CustomerID)
+	assert.Equal(t, 5500000.0, got.Loan.Outstanding)
+	assert.Len(t, got.Bills, 50)
+}
+
+// TestCreateLoansBatch_CSV tests POST /loans/batch reads loan requests from CSV and numbers rows by line
+func TestCreateLoansBatch_CSV(t *testing.T) {
+	customer := fmt.Sprintf("cust-%d", randomNumber())
+	file := strings.Join([]string{
+		"customer_id,name,amount,period,interest_rate",
+		customer + ",Group A,5000000,50,10",
+		customer + ",Group A,abc,50,10",
+		customer + ",Group A,3000000,50,",
+	}, "\n")
+
+	req := httptest.NewRequest(http.MethodPost, "/loans/batch", strings.NewReader(file))
+	req.Header.Set(echo.HeaderContentType, "text/csv")
+	rec := httptest.NewRecorder()
+	newServer().ServeHTTP(rec, req)
+
+	report := batchReport(t, rec)
+	assert.Equal(t, []string{
+		model.BatchLoanCreated + " ",
+		model.BatchLoanRejected + " VALIDATION_FAILED",
+		model.BatchLoanCreated + " ",
+	}, batchStatuses(report))
+	assert.Equal(t, []int{2, 3, 4}, []int{report.Results[0].Row, report.Results[1].Row, report.Results[2].Row})
+	assert.Contains(t, report.Results[1].Message, "amount")
+
+	rec = serve(newServer(), http.MethodGet, "/bills/"+report.Results[2].LoanID, nil, nil)
+	got, err := unmarshalResponse[model.LoanWithBills](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, got.Loan.InterestRate)
+	assert.Equal(t, 3300000.0, got.Loan.TotalAmount)
+
+	req = httptest.NewRequest(http.MethodPost, "/loans/batch", strings.NewReader("customer_id,amount\n"))
+	req.Header.Set(echo.HeaderContentType, "text/csv")
+	rec = httptest.NewRecorder()
+	newServer().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "period")
+}
+
+// TestCreateLoansBatch_Eligibility tests earlier rows of a batch count towards a customer's limits
+func TestCreateLoansBatch_Eligibility(t *testing.T) {
+	customer := seedCustomer(t, model.KYCStatusVerified)
+	cfg := testConfig()
+	cfg.Loan.MaxActiveLoans = 2
+	loans := []model.ProductLoan{loanRequestFor(customer.ID), loanRequestFor(customer.ID), loanRequestFor(customer.ID)}
+
+	report := batchReport(t, serve(newServerWith(cfg), http.MethodPost, "/loans/batch", loans, nil))
+	assert.Equal(t, []string{
+		model.BatchLoanCreated + " ",
+		model.BatchLoanCreated + " ",
+		model.BatchLoanRejected + " " + usecase.ErrCustomerNotEligible.Code,
+	}, batchStatuses(report))
+	assert.Contains(t, report.Results[2].Message, "limit is 2")
+}
+
+// TestCreateLoansBatch_Chunks tests a batch larger than one storage chunk is created in full
+func TestCreateLoansBatch_Chunks(t *testing.T) {
+	loans := make([]model.ProductLoan, 0, 150)
+	for range 150 {
+		loans = append(loans, loanRequestFor(fmt.Sprintf("cust-%d", randomNumber())))
+	}
+
+	report := batchReport(t, serve(newServer(), http.MethodPost, "/loans/batch", loans, nil))
+	assert.Equal(t, 150, report.Created)
+
+	last := report.Results[149]
+	assert.Equal(t, 150, last.Row)
+	rec := serve(newServer(), http.MethodGet, "/bills/"+last.LoanID, nil, nil)
+	got, err := unmarshalResponse[model.LoanWithBills](rec)
+	assert.NoError(t, err)
+	assert.Len(t, got.Bills, 50)
+}
+
+// TestCreateLoansBatch_Invalid tests POST /loans/batch rejects empty and oversized batches with 400
+func TestCreateLoansBatch_Invalid(t *testing.T) {
+	rec := serve(newServer(), http.MethodPost, "/loans/batch", []model.Loan{}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(newServer(), http.MethodPost, "/loans/batch", make([]model.Loan, usecase.MaxLoanBatch+1), nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(newServer(), http.MethodPost, "/loans/batch", loanRequestFor("cust-1"), nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// TestCreateLoansBatch_Permissions tests only roles that create loans may create them in bulk
+func TestCreateLoansBatch_Permissions(t *testing.T) {
+	cfg, _ := authConfig(t)
+	e := newServerWith(cfg)
+	loans := []model.ProductLoan{loanRequestFor(fmt.Sprintf("cust-%d", randomNumber()))}
+
+	token := signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), "officer-1", []string{auth.RoleCollector}, time.Hour)
+	assert.Equal(t, http.StatusForbidden, serve(e, http.MethodPost, "/loans/batch", loans, bearer(token)).Code)
+
+	token = signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), "ops-1", []string{auth.RoleOps}, time.Hour)
+	assert.Equal(t, http.StatusOK, serve(e, http.MethodPost, "/loans/batch", loans, bearer(token)).Code)
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"billing/internal/batch"
+	"billing/internal/logging"
+	"billing/internal/model"
+	"billing/internal/util"
+	"billing/pkg/db"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// TestRequestID_Propagated tests the caller's X-Request-ID is echoed back
+func TestRequestID_Propagated(t *testing.T) {
+	httpReq := httptest.NewRequest(http.MethodGet, "/bills/missing-loan", nil)
+	httpReq.Header.Set("X-Request-ID", "req-123")
+	rec := httptest.NewRecorder()
+	newServer().ServeHTTP(rec, httpReq)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "req-123", rec.Header().Get("X-Request-ID"))
+}
+
+// TestRequestID_Generated tests a request without X-Request-ID is given one
+func TestRequestID_Generated(t *testing.T) {
+	rec := callAPI(APIRequest{Method: http.MethodGet, Path: "/bills/missing-loan"})
+	assert.NotEmpty(t, rec.Header().Get("X-Request-ID"))
+}
+
+// TestLogger_RequestIDAndRedaction tests log lines carry the request ID and mask customer IDs
+func TestLogger_RequestIDAndRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, slog.LevelInfo)
+
+	ctx := logging.WithRequestID(context.Background(), "req-456")
+	logger.InfoContext(ctx, "loan created", "customer_id", "cust-987654")
+
+	var line map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "req-456", line["request_id"])
+	assert.Equal(t, "*******7654", line["customer_id"])
+	assert.NotContains(t, buf.String(), "cust-987654")
+}
+
+// TestDBLogger_ParameterisedSQL tests failed statements are logged without their bound values and not-found lookups are not logged
+func TestDBLogger_ParameterisedSQL(t *testing.T) {
+	conn, err := db.Open(db.Config{Driver: db.DriverSQLite, DSN: filepath.Join(t.TempDir(), "logger.db")})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	session := conn.Session(&gorm.Session{Logger: db.NewLogger(logging.New(&buf, slog.LevelInfo))})
+
+	var loan model.Loan
+	assert.Error(t, session.Table("no_such_table").Where("customer_id = ?", "cust-987654").First(&loan).Error)
+	assert.Contains(t, buf.String(), "sql failed")
+	assert.NotContains(t, buf.String(), "cust-987654")
+
+	buf.Reset()
+	_, err = db.MigrateUp(conn)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, session.Where("customer_id = ?", "cust-987654").First(&loan).Error, gorm.ErrRecordNotFound)
+	assert.Empty(t, buf.String())
+}
+
+// TestEOD_LogsRunAttributes tests end-of-day log lines carry the run ID and business date
+func TestEOD_LogsRunAttributes(t *testing.T) {
+	conn, err := db.Open(db.Config{Driver: db.DriverSQLite, DSN: filepath.Join(t.TempDir(), "eod.db")})
+	assert.NoError(t, err)
+	_, err = db.MigrateUp(conn)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	runner := batch.Runner{DB: conn, ReportDir: t.TempDir(), Logger: logging.New(&buf, slog.LevelInfo)}
+	run, err := runner.Run(context.Background(), util.GetCurrentTime())
+	assert.NoError(t, err)
+
+	var line map[string]any
+	assert.NoError(t, json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &line))
+	assert.Equal(t, "eod run completed", line["msg"])
+	assert.Equal(t, run.ID, line["run_id"])
+	assert.Equal(t, run.BusinessDate, line["business_date"])
+}
@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"billing/internal/config"
+	"billing/internal/repository"
+	"billing/internal/usecase"
+	"billing/pkg/db"
+	"log"
+	"os"
+	"testing"
+)
+
+// TestMain runs the whole suite against SQLite, the in-memory backend and,
+// when BILLING_TEST_POSTGRES_DSN points at a reachable server, PostgreSQL.
+func TestMain(m *testing.M) {
+	os.Setenv("BILLING_DEFAULT_PRODUCT_CODE", usecase.DefaultProductCode)
+	os.Setenv("BILLING_AUTH_DISABLED", "true")
+	os.Setenv("BILLING_REQUIRE_REGISTERED_CUSTOMER", "false")
+
+	sqliteDB, err := db.InitAndMigrate(config.Default().DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	backends := []testBackend{
+		{name: BackendSQLite, repos: repository.NewGormRepositories(sqliteDB), db: sqliteDB},
+		{name: BackendMemory, repos: repository.NewMemoryRepositories()},
+	}
+
+	if dsn := os.Getenv("BILLING_TEST_POSTGRES_DSN"); dsn != "" {
+		pg, err := db.Open(db.Config{Driver: db.DriverPostgres, DSN: dsn})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := db.MigrateUp(pg); err != nil {
+			log.Fatal(err)
+		}
+		backends = append(backends, testBackend{name: BackendPostgres, repos: repository.NewGormRepositories(pg), db: pg})
+	}
+
+	code := 0
+	for _, b := range backends {
+		log.Println("running suite against", b.name)
+		backend = b
+		if c := m.Run(); c != 0 {
+			code = c
+		}
+	}
+
+	os.Exit(code)
+}
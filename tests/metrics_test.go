@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"billing/internal/model"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scrapeMetrics(t *testing.T, serve http.Handler) string {
+	rec := httptest.NewRecorder()
+	serve.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+// TestMetrics_Payments tests GET /metrics counts payments by outcome and observes route latency
+func TestMetrics_Payments(t *testing.T) {
+	loan, err := seedData()
+	if err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	e := newServer()
+
+	bill := loan.Bills[0]
+	for _, amount := range []float64{1, bill.Amount} {
+		body, _ := json.Marshal(model.MakePaymentRequest{
+			PaymentAmount: amount,
+			PaymentDate:   bill.DueDate,
+		})
+		httpReq := httptest.NewRequest(http.MethodPost, "/bills/"+loan.Loan.ID+"/payments", bytes.NewReader(body))
+		httpReq.Header.Set("Content-Type", "application/json")
+		e.ServeHTTP(httptest.NewRecorder(), httpReq)
+	}
+
+	body := scrapeMetrics(t, e)
+	assert.Contains(t, body, `billing_payments_total{outcome="insufficient_amount"} 1`)
+	assert.Contains(t, body, `billing_payments_total{outcome="success"} 1`)
+	assert.Contains(t, body, `billing_payment_amount_total{outcome="insufficient_amount"} 1`)
+	assert.Contains(t, body, `billing_http_request_duration_seconds_count{method="POST",route="/bills/:loan_id/payments",status="200"} 1`)
+	assert.Contains(t, body, `billing_http_request_duration_seconds_count{method="POST",route="/bills/:loan_id/payments",status="400"} 1`)
+	assert.NotContains(t, body, `route="/metrics"`)
+}
+
+// TestMetrics_DelinquentLoans tests GET /metrics reports delinquent loans computed from storage
+func TestMetrics_DelinquentLoans(t *testing.T) {
+	if _, err := seedData(); err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	defer addTimeNow(3)()
+
+	body := scrapeMetrics(t, newServer())
+	assert.Contains(t, body, "billing_delinquent_loans ")
+	assert.Contains(t, body, "billing_delinquent_outstanding ")
+	assert.NotContains(t, body, "billing_delinquent_loans 0\n")
+}
+
+// TestMetrics_LoansCreated tests GET /metrics counts loans created
+func TestMetrics_LoansCreated(t *testing.T) {
+	e := newServer()
+	body, _ := json.Marshal(model.Loan{
+		CustomerID:   "cust-metrics",
+		Name:         "Test Loan",
+		Period:       50,
+		Amount:       5000000,
+		InterestRate: 10,
+	})
+	httpReq := httptest.NewRequest(http.MethodPost, "/bills", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httpReq)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	assert.Contains(t, scrapeMetrics(t, e), "billing_loans_created_total 1\n")
+}
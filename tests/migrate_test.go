@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"billing/internal/model"
+	"billing/internal/repository"
+	"billing/internal/usecase"
+	"billing/pkg/db"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// legacyLoan and legacyBilling are the models the original service passed to
+// AutoMigrate, before versioned migrations.
+type legacyLoan struct {
+	ID           string
+	CustomerID   string
+	Name         string
+	Period       int
+	Amount       float64
+	InterestRate float64
+	TotalAmount  float64
+	Outstanding  float64
+	Status       string
+	CreatedAt    time.Time
+}
+
+func (legacyLoan) TableName() string { return "loans" }
+
+type legacyBilling struct {
+	ID          string
+	LoanID      string
+	Sequence    int
+	Date        time.Time
+	DueDate     time.Time
+	PaymentDate *time.Time
+	Amount      float64
+	CreatedAt   time.Time
+}
+
+func (legacyBilling) TableName() string { return "billings" }
+
+// TestMigrateUp_AdoptsLegacySchema tests a database created by AutoMigrate is upgraded in place, keeping its loans and bills
+func TestMigrateUp_AdoptsLegacySchema(t *testing.T) {
+	t.Run(db.DriverSQLite, func(t *testing.T) {
+		conn, err := db.Open(db.Config{Driver: db.DriverSQLite, DSN: filepath.Join(t.TempDir(), "legacy.db")})
+		assert.NoError(t, err)
+		testAdoptLegacySchema(t, conn)
+	})
+
+	t.Run(db.DriverPostgres, func(t *testing.T) {
+		dsn := os.Getenv("BILLING_TEST_POSTGRES_DSN")
+		if dsn == "" {
+			t.Skip("BILLING_TEST_POSTGRES_DSN is not set")
+		}
+		schema := fmt.Sprintf("legacy_%d", randomNumber())
+		admin, err := db.Open(db.Config{Driver: db.DriverPostgres, DSN: dsn})
+		assert.NoError(t, err)
+		assert.NoError(t, admin.Exec("CREATE SCHEMA "+schema).Error)
+		t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+		conn, err := db.Open(db.Config{Driver: db.DriverPostgres, DSN: withSearchPath(dsn, schema)})
+		assert.NoError(t, err)
+		testAdoptLegacySchema(t, conn)
+	})
+}
+
+// withSearchPath points a libpq URL or keyword DSN at schema.
+func withSearchPath(dsn, schema string) string {
+	switch {
+	case !strings.Contains(dsn, "://"):
+		return dsn + " search_path=" + schema
+	case strings.Contains(dsn, "?"):
+		return dsn + "&search_path=" + schema
+	default:
+		return dsn + "?search_path=" + schema
+	}
+}
+
+func testAdoptLegacySchema(t *testing.T, conn *gorm.DB) {
+	assert.NoError(t, conn.AutoMigrate(&legacyLoan{}, &legacyBilling{}))
+
+	created := time.Date(2025, time.October, 18, 12, 0, 0, 0, time.UTC)
+	paid := created.AddDate(0, 0, 7)
+	loan := legacyLoan{ID: "legacy-loan", CustomerID: "legacy-customer", Name: "Martin", Period: 2, Amount: 200000,
+		InterestRate: 10, TotalAmount: 220000, Outstanding: 110000, Status: model.LoanStatusInProgress, CreatedAt: created}
+	bills := []legacyBilling{
+		{ID: "legacy-bill-1", LoanID: loan.ID, Sequence: 1, Date: created, DueDate: paid, PaymentDate: &paid, Amount: 110000, CreatedAt: created},
+		{ID: "legacy-bill-2", LoanID: loan.ID, Sequence: 2, Date: created, DueDate: created.AddDate(0, 0, 14), Amount: 110000, CreatedAt: created},
+	}
+	assert.NoError(t, conn.Create(&loan).Error)
+	assert.NoError(t, conn.Create(&bills).Error)
+
+	applied, err := db.MigrateUp(conn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	latest, err := db.LatestVersion(conn.Dialector.Name())
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, applied) {
+		assert.Equal(t, latest, applied[len(applied)-1])
+	}
+	assert.NoError(t, db.CheckReady(context.Background(), conn))
+
+	ctx := context.Background()
+	repos := repository.NewGormRepositories(conn)
+	productCode, err := repos.Loans.ProductCode(ctx, loan.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, usecase.DefaultProductCode, productCode)
+	stored, err := repos.Loans.GetByID(ctx, loan.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, loan.CustomerID, stored.CustomerID)
+		assert.Equal(t, loan.Outstanding, stored.Outstanding)
+		assert.True(t, created.Equal(stored.CreatedAt))
+	}
+	_, err = repos.Products.GetByCode(ctx, usecase.DefaultProductCode)
+	assert.NoError(t, err)
+
+	storedBills, err := repos.Billings.GetByLoanID(ctx, loan.ID)
+	if assert.NoError(t, err) && assert.Len(t, storedBills, 2) {
+		if assert.NotNil(t, storedBills[0].PaymentDate) {
+			assert.True(t, paid.Equal(*storedBills[0].PaymentDate))
+		}
+		assert.Nil(t, storedBills[1].PaymentDate)
+	}
+
+	// The adopted schema takes constraints the AutoMigrate one lacked.
+	orphan := model.Billing{ID: "legacy-orphan", LoanID: "no-such-loan", Sequence: 1, Date: created, DueDate: created, Amount: 1, CreatedAt: created}
+	assert.Error(t, repos.Billings.CreateBatch(ctx, []model.Billing{orphan}))
+
+	applied, err = db.MigrateUp(conn)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+}
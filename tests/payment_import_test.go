@@ -0,0 +1,283 @@
+package tests
+
+import (
+	"billing/internal/auth"
+	"billing/internal/model"
+	"billing/internal/payfile"
+	"billing/internal/repository"
+	"billing/internal/usecase"
+	"billing/internal/validation"
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// uploadPayments posts a payment file to POST /payments/import as a multipart form.
+func uploadPayments(e *echo.Echo, filename, content string, header http.Header) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", filename)
+	part.Write([]byte(content))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/payments/import", &body)
+	req.Header.Set(echo.HeaderContentType, form.FormDataContentType())
+	for k, values := range header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func importReport(t *testing.T, rec *httptest.ResponseRecorder) model.PaymentImportReport {
+	if !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	report, err := unmarshalResponse[model.PaymentImportReport](rec)
+	assert.NoError(t, err)
+	return report
+}
+
+func importStatuses(report model.PaymentImportReport) []string {
+	statuses := make([]string, 0, len(report.Results))
+	for _, r := range report.Results {
+		statuses = append(statuses, r.Status+" "+r.Code)
+	}
+	return statuses
+}
+
+// TestImportPayments_CSV tests POST /payments/import applies each row of a CSV file and reports rejected and duplicate rows,
+// and that importing the same file again pays nothing twice
+func TestImportPayments_CSV(t *testing.T) {
+	restore := addTimeNow(-10)
+	loan := createLoanFor(t, fmt.Sprintf("cust-%d", randomNumber()), 5000000)
+	restore()
+
+	ref := fmt.Sprintf("BANK-%d", randomNumber())
+	week := func(n int) string { return loan.CreatedAt.AddDate(0, 0, 7*n).Format(time.DateOnly) }
+	file := strings.Join([]string{
+		"payment_date,amount,loan_id,reference,narrative",
+		fmt.Sprintf("%s,110000,%s,%s-1,week 1", week(1), loan.ID, ref),
+		fmt.Sprintf("%s,110000,%s,%s-1,week 1 again", week(1), loan.ID, ref),
+		fmt.Sprintf("%s,100000,%s,%s-2,short", week(2), loan.ID, ref),
+		fmt.Sprintf("%s,110000,missing-%d,%s-3,unknown loan", week(2), randomNumber(), ref),
+		fmt.Sprintf("%s,abc,%s,%s-4,bad amount", week(2), loan.ID, ref),
+		fmt.Sprintf("%s,110000,%s,%s-5,week 2", week(2), loan.ID, ref),
+	}, "\n")
+
+	report := importReport(t, uploadPayments(newServer(), "bank.csv", file, nil))
+	assert.Equal(t, "bank.csv", report.Source)
+	assert.Equal(t, 6, report.Rows)
+	assert.Equal(t, []string{
+		model.ImportApplied + " ",
+		model.ImportDuplicate + " " + usecase.CodeDuplicateReference,
+		model.ImportRejected + " " + usecase.ErrInsufficientAmount.Code,
+		model.ImportRejected + " LOAN_NOT_FOUND",
+		model.ImportRejected + " " + "VALIDATION_FAILED",
+		model.ImportApplied + " ",
+	}, importStatuses(report))
+	assert.Equal(t, []int{2, 3, 4, 5, 6, 7}, []int{
+		report.Results[0].Line, report.Results[1].Line, report.Results[2].Line,
+		report.Results[3].Line, report.Results[4].Line, report.Results[5].Line,
+	})
+	assert.Equal(t, 2, report.Applied)
+	assert.Equal(t, 3, report.Rejected)
+	assert.Equal(t, 1, report.Duplicate)
+	assert.Contains(t, report.Results[4].Message, "amount")
+
+	report = importReport(t, uploadPayments(newServer(), "bank.csv", file, nil))
+	assert.Equal(t, 0, report.Applied)
+	assert.Equal(t, 3, report.Duplicate)
+	assert.Equal(t, model.ImportDuplicate, report.Results[0].Status)
+	assert.Equal(t, model.ImportDuplicate, report.Results[5].Status)
+
+	rec := serve(newServer(), http.MethodGet, "/bills/"+loan.ID, nil, nil)
+	got, err := unmarshalResponse[model.LoanWithBills](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, loan.TotalAmount-220000, got.Loan.Outstanding)
+	assert.NotNil(t, got.Bills[0].PaymentDate)
+	assert.NotNil(t, got.Bills[1].PaymentDate)
+	assert.Nil(t, got.Bills[2].PaymentDate)
+}
+
+// TestImportPayments_RejectedRowRetried tests a rejected row's reference can be imported again once corrected
+func TestImportPayments_RejectedRowRetried(t *testing.T) {
+	restore := addTimeNow(-10)
+	loan := createLoanFor(t, fmt.Sprintf("cust-%d", randomNumber()), 5000000)
+	restore()
+
+	ref := fmt.Sprintf("BANK-%d", randomNumber())
+	date := loan.CreatedAt.AddDate(0, 0, 7).Format(time.DateOnly)
+	row := "reference,loan_id,amount,payment_date\n%s,%s,%d,%s\n"
+
+	report := importReport(t, uploadPayments(newServer(), "day1.csv", fmt.Sprintf(row, ref, loan.ID, 1000, date), nil))
+	assert.Equal(t, 1, report.Rejected)
+
+	report = importReport(t, uploadPayments(newServer(), "day1-fixed.csv", fmt.Sprintf(row, ref, loan.ID, 110000, date), nil))
+	assert.Equal(t, 1, report.Applied)
+}
+
+// TestImportPayments_MT940 tests POST /payments/import reads the credit entries of an MT940 statement sent as the raw body
+func TestImportPayments_MT940(t *testing.T) {
+	restore := addTimeNow(-10)
+	loan := createLoanFor(t, fmt.Sprintf("cust-%d", randomNumber()), 5000000)
+	restore()
+
+	due := loan.CreatedAt.AddDate(0, 0, 7).Format("060102")
+	ref := fmt.Sprintf("MT%d", randomNumber())
+	statement := strings.Join([]string{
+		":20:STATEMENT1",
+		":25:ACCOUNT/123",
+		":28C:1/1",
+		":60F:C" + due + "IDR0,00",
+		":61:" + due + "C110000,00NTRFNONREF//" + ref,
+		":86:REPAYMENT",
+		"/LOAN/" + loan.ID + "/ WEEK 1",
+		":61:" + due + "D2500,00NCHGNONREF",
+		":86:BANK CHARGES",
+		":62F:C" + due + "IDR107500,00",
+	}, "\r\n")
+
+	req := httptest.NewRequest(http.MethodPost, "/payments/import?filename=statement.sta", strings.NewReader(statement))
+	req.Header.Set(echo.HeaderContentType, "text/plain")
+	rec := httptest.NewRecorder()
+	newServer().ServeHTTP(rec, req)
+
+	report := importReport(t, rec)
+	assert.Equal(t, "statement.sta", report.Source)
+	if assert.Len(t, report.Results, 2) {
+		applied := report.Results[0]
+		assert.Equal(t, model.ImportApplied, applied.Status, applied.Message)
+		assert.Equal(t, ref, applied.Reference)
+		assert.Equal(t, loan.ID, applied.LoanID)
+		assert.Equal(t, 110000.0, applied.Amount)
+		assert.Equal(t, 5, applied.Line)
+
+		assert.Equal(t, model.ImportRejected, report.Results[1].Status)
+		assert.Equal(t, 8, report.Results[1].Line)
+	}
+}
+
+// TestImportPayments_InvalidFile tests POST /payments/import rejects files it cannot read with 400
+func TestImportPayments_InvalidFile(t *testing.T) {
+	rec := uploadPayments(newServer(), "bank.csv", "reference,amount\nR1,110000\n", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "loan_id, payment_date")
+
+	rec = uploadPayments(newServer(), "bank.csv", "", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/payments/import?format=xml", strings.NewReader("<payments/>"))
+	rec = httptest.NewRecorder()
+	newServer().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), validation.CodeNotAllowed)
+}
+
+// TestImportPayments_Permissions tests only ops may import payment files
+func TestImportPayments_Permissions(t *testing.T) {
+	cfg, _ := authConfig(t)
+	e := newServerWith(cfg)
+	file := "reference,loan_id,amount,payment_date\n"
+
+	token := signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), "officer-1", []string{auth.RoleCollector}, time.Hour)
+	assert.Equal(t, http.StatusForbidden, uploadPayments(e, "bank.csv", file, bearer(token)).Code)
+
+	token = signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), "ops-1", []string{auth.RoleOps}, time.Hour)
+	assert.Equal(t, http.StatusOK, uploadPayments(e, "bank.csv", file, bearer(token)).Code)
+}
+
+// crashingImports stands in for an import that dies after recording a
+// reference but before its payment commits: the extra payment it adds is for
+// a bill that does not exist, which fails the transaction from inside.
+type crashingImports struct {
+	repository.PaymentImportRepository
+}
+
+func (c crashingImports) Apply(ctx context.Context, rec *model.PaymentImport, payments []repository.BillPayment) error {
+	return c.PaymentImportRepository.Apply(ctx, rec, append(payments, repository.BillPayment{LoanID: "crashed", BillingID: "crashed"}))
+}
+
+func importRows(loan model.LoanWithBills) []payfile.Row {
+	bill := loan.Bills[0]
+	return []payfile.Row{{
+		Line:        2,
+		Reference:   fmt.Sprintf("BANK-%d", randomNumber()),
+		LoanID:      loan.Loan.ID,
+		Amount:      bill.Amount,
+		PaymentDate: bill.DueDate,
+	}}
+}
+
+func importUsecase(imports repository.PaymentImportRepository) *usecase.PaymentImportUsecase {
+	return &usecase.PaymentImportUsecase{
+		Loans:   &usecase.LoanUsecase{Loans: backend.repos.Loans, Billings: backend.repos.Billings},
+		Imports: imports,
+	}
+}
+
+// TestImportPayments_CrashBeforePayment tests a run that dies between recording a reference and posting its payment leaves neither behind, so the next run pays the row
+func TestImportPayments_CrashBeforePayment(t *testing.T) {
+	loan, err := seedData()
+	if err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	rows := importRows(loan)
+	ctx := context.Background()
+
+	report, err := importUsecase(crashingImports{backend.repos.Imports}).Import(ctx, "day1.csv", rows)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.Applied)
+	applied, err := backend.repos.Imports.Applied(ctx, rows[0].Reference)
+	assert.NoError(t, err)
+	assert.False(t, applied)
+	bills, err := backend.repos.Billings.GetByLoanID(ctx, loan.Loan.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, bills[0].PaymentDate)
+
+	report, err = importUsecase(backend.repos.Imports).Import(ctx, "day1.csv", rows)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Applied)
+	assert.Equal(t, 0, report.Duplicate)
+
+	report, err = importUsecase(backend.repos.Imports).Import(ctx, "day1.csv", rows)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Duplicate)
+}
+
+// TestImportPayments_PendingClaimRetried tests a reference left pending by an import that stopped before paying is imported again rather than reported as a duplicate
+func TestImportPayments_PendingClaimRetried(t *testing.T) {
+	database := requireDB(t)
+	loan, err := seedData()
+	if err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	rows := importRows(loan)
+	assert.NoError(t, database.Create(&model.PaymentImport{
+		Reference:   rows[0].Reference,
+		LoanID:      rows[0].LoanID,
+		Amount:      rows[0].Amount,
+		PaymentDate: rows[0].PaymentDate,
+		Status:      model.PaymentImportPending,
+		Source:      "crashed.csv",
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}).Error)
+
+	report, err := importUsecase(backend.repos.Imports).Import(context.Background(), "day1.csv", rows)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Applied)
+}
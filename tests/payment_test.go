@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"billing/internal/model"
+	"billing/internal/repository"
+	"billing/internal/usecase"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func billPayment(bill model.Billing) repository.BillPayment {
+	return repository.BillPayment{LoanID: bill.LoanID, BillingID: bill.ID, Amount: bill.Amount, PaymentDate: bill.DueDate}
+}
+
+// TestApplyPayments_BillPaidOnce tests a bill paid twice, as by two racing requests, is only paid and subtracted once
+func TestApplyPayments_BillPaidOnce(t *testing.T) {
+	loan, err := seedData()
+	if err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	ctx := context.Background()
+	payment := billPayment(loan.Bills[0])
+
+	assert.NoError(t, backend.repos.Loans.ApplyPayments(ctx, []repository.BillPayment{payment}))
+	assert.ErrorIs(t, backend.repos.Loans.ApplyPayments(ctx, []repository.BillPayment{payment}), repository.ErrConflict)
+
+	stored, err := backend.repos.Loans.GetByID(ctx, loan.Loan.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, loan.Loan.TotalAmount-payment.Amount, stored.Outstanding)
+}
+
+// TestApplyPayments_AllOrNothing tests a batch with one bill already paid applies none of its payments
+func TestApplyPayments_AllOrNothing(t *testing.T) {
+	loan, err := seedData()
+	if err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	ctx := context.Background()
+	assert.NoError(t, backend.repos.Loans.ApplyPayments(ctx, []repository.BillPayment{billPayment(loan.Bills[0])}))
+
+	err = backend.repos.Loans.ApplyPayments(ctx, []repository.BillPayment{billPayment(loan.Bills[1]), billPayment(loan.Bills[0])})
+	assert.ErrorIs(t, err, repository.ErrConflict)
+
+	bills, err := backend.repos.Billings.GetByLoanID(ctx, loan.Loan.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, bills[1].PaymentDate, "The bill before the conflict should be rolled back")
+	stored, err := backend.repos.Loans.GetByID(ctx, loan.Loan.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, loan.Loan.TotalAmount-loan.Bills[0].Amount, stored.Outstanding)
+}
+
+// TestApplyPayments_CompletesLoan tests paying the last bill completes the loan from its stored outstanding
+func TestApplyPayments_CompletesLoan(t *testing.T) {
+	// Starting a server seeds the default product the loan refers to.
+	newServer()
+	ctx := context.Background()
+	created := time.Now().UTC().Truncate(time.Second)
+	loan := model.Loan{
+		ID:           fmt.Sprintf("loan-last-bill-%d", randomNumber()),
+		CustomerID:   fmt.Sprintf("cust-%d", randomNumber()),
+		Period:       2,
+		Amount:       200000,
+		TotalAmount:  220000,
+		Outstanding:  110000,
+		Status:       model.LoanStatusDelinquent,
+		CreatedAt:    created,
+		InterestRate: 10,
+	}
+	bill := model.Billing{
+		ID:        loan.ID + "-02",
+		LoanID:    loan.ID,
+		Sequence:  2,
+		Date:      created,
+		DueDate:   created.AddDate(0, 0, 14),
+		Amount:    110000,
+		CreatedAt: created,
+	}
+	assert.NoError(t, backend.repos.Loans.CreateBatch(ctx, []model.ProductLoan{{Loan: loan, ProductCode: usecase.DefaultProductCode}}, []model.Billing{bill}))
+
+	assert.NoError(t, backend.repos.Loans.ApplyPayments(ctx, []repository.BillPayment{billPayment(bill)}))
+
+	stored, err := backend.repos.Loans.GetByID(ctx, loan.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), stored.Outstanding)
+	assert.Equal(t, model.LoanStatusCompleted, stored.Status)
+}
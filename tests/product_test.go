@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"billing/internal/model"
+	"billing/internal/usecase"
+	"billing/internal/util"
+	"billing/internal/validation"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// seedProduct adds product to the catalogue unless it is there already.
+func seedProduct(t *testing.T, product model.Product) {
+	// Starting a server seeds the default catalogue first.
+	newServer()
+	product.CreatedAt = util.GetCurrentTime()
+	if err := backend.repos.Products.CreateIfMissing(context.Background(), &product); err != nil {
+		t.Fatalf("Failed to seed product: %v", err)
+	}
+}
+
+func productLoan(productCode string, amount float64, period int, interestRate float64) model.ProductLoan {
+	return model.ProductLoan{
+		Loan: model.Loan{
+			CustomerID:   fmt.Sprintf("cust-%d", randomNumber()),
+			Name:         "Product Loan",
+			Amount:       amount,
+			Period:       period,
+			InterestRate: interestRate,
+		},
+		ProductCode: productCode,
+	}
+}
+
+func fieldCodes(fields validation.Errors) map[string]string {
+	codes := map[string]string{}
+	for _, f := range fields {
+		codes[f.Field] = f.Code
+	}
+	return codes
+}
+
+// TestCreateBills_UnknownProduct tests POST /bills rejects a product_code missing from the catalogue
+func TestCreateBills_UnknownProduct(t *testing.T) {
+	rec := serve(newServer(), http.MethodPost, "/bills", productLoan("NO-SUCH-PRODUCT", 5000000, 50, 10), nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"product_code": validation.CodeUnknownReference}, fieldCodes(detail.Fields))
+}
+
+// TestCreateBills_ProductCodeRequired tests POST /bills rejects a loan without product_code when no default product is configured
+func TestCreateBills_ProductCodeRequired(t *testing.T) {
+	cfg := testConfig()
+	cfg.Loan.DefaultProductCode = ""
+	rec := serve(newServerWith(cfg), http.MethodPost, "/bills", productLoan("", 5000000, 50, 10), nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"product_code": validation.CodeRequired}, fieldCodes(detail.Fields))
+
+	rec = serve(newServer(), http.MethodPost, "/bills", productLoan("", 5000000, 50, 10), nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	loan, err := unmarshalResponse[model.LoanWithBills](rec)
+	assert.NoError(t, err)
+	productCode, err := backend.repos.Loans.ProductCode(context.Background(), loan.Loan.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, usecase.DefaultProductCode, productCode, "The configured default product should be applied")
+}
+
+// TestCreateBills_ProductLimits tests POST /bills reports every amount, period and rate limit of the product the loan breaks
+func TestCreateBills_ProductLimits(t *testing.T) {
+	seedProduct(t, model.Product{
+		Code:         "TEST-LIMITS",
+		MinAmount:    1000000,
+		MaxAmount:    2000000,
+		Tenors:       []int{10, 20},
+		InterestRate: 12,
+		Frequency:    usecase.FrequencyWeekly,
+	})
+	e := newServer()
+
+	rec := serve(e, http.MethodPost, "/bills", productLoan("TEST-LIMITS", 500000, 15, 10), nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"amount":        validation.CodeOutOfRange,
+		"period":        validation.CodeNotAllowed,
+		"interest_rate": validation.CodeNotAllowed,
+	}, fieldCodes(detail.Fields))
+
+	rec = serve(e, http.MethodPost, "/bills", productLoan("TEST-LIMITS", 2500000, 20, 12), nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	detail, err = unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"amount": validation.CodeOutOfRange}, fieldCodes(detail.Fields))
+
+	// The product's rate applies when the request leaves it out.
+	rec = serve(e, http.MethodPost, "/bills", productLoan("TEST-LIMITS", 2000000, 20, 0), nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	loan, err := unmarshalResponse[model.LoanWithBills](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(12), loan.Loan.InterestRate)
+	assert.Equal(t, float64(2240000), loan.Loan.TotalAmount)
+}
+
+// TestCreateBills_ProductFrequency tests bills fall due every two weeks or every month following the product's frequency
+func TestCreateBills_ProductFrequency(t *testing.T) {
+	for _, tc := range []struct {
+		frequency string
+		next      func(time.Time) time.Time
+	}{
+		{usecase.FrequencyBiweekly, func(d time.Time) time.Time { return d.AddDate(0, 0, 14) }},
+		{usecase.FrequencyMonthly, func(d time.Time) time.Time { return d.AddDate(0, 1, 0) }},
+	} {
+		t.Run(tc.frequency, func(t *testing.T) {
+			code := "TEST-" + tc.frequency
+			seedProduct(t, model.Product{
+				Code:         code,
+				MinAmount:    1000000,
+				MaxAmount:    10000000,
+				Tenors:       []int{12},
+				InterestRate: 12,
+				Frequency:    tc.frequency,
+			})
+
+			rec := serve(newServer(), http.MethodPost, "/bills", productLoan(code, 3000000, 12, 12), nil)
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			loan, err := unmarshalResponse[model.LoanWithBills](rec)
+			assert.NoError(t, err)
+			if !assert.Len(t, loan.Bills, 12) {
+				return
+			}
+
+			due := loan.Loan.CreatedAt
+			for _, bill := range loan.Bills {
+				due = tc.next(due)
+				assert.True(t, due.Equal(bill.DueDate), "bill %d due %s, expected %s", bill.Sequence, bill.DueDate, due)
+			}
+		})
+	}
+}
+
+// TestCreateBills_ProductAdminFee tests the product's admin fee is added to the total and spread over the installments
+func TestCreateBills_ProductAdminFee(t *testing.T) {
+	seedProduct(t, model.Product{
+		Code:         "TEST-ADMIN-FEE",
+		MinAmount:    1000000,
+		MaxAmount:    10000000,
+		Tenors:       []int{10},
+		InterestRate: 12,
+		Frequency:    usecase.FrequencyWeekly,
+		AdminFee:     50000,
+	})
+
+	rec := serve(newServer(), http.MethodPost, "/bills", productLoan("TEST-ADMIN-FEE", 1000000, 10, 12), nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	loan, err := unmarshalResponse[model.LoanWithBills](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1170000), loan.Loan.TotalAmount)
+	assert.Equal(t, float64(1170000), loan.Loan.Outstanding)
+	if assert.NotEmpty(t, loan.Bills) {
+		assert.Equal(t, float64(117000), loan.Bills[0].Amount)
+	}
+}
+
+// TestSearchLoans_ProductCode tests GET /loans reports and filters by the product while POST /bills answers with the original loan fields
+func TestSearchLoans_ProductCode(t *testing.T) {
+	customerID := fmt.Sprintf("cust-%d", randomNumber())
+	rec := serve(newServer(), http.MethodPost, "/bills", loanRequestFor(customerID), nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "product_code")
+
+	resp := searchLoans(t, url.Values{"customer_id": {customerID}, "product_code": {usecase.DefaultProductCode}}, nil)
+	if assert.Len(t, resp.Loans, 1) {
+		assert.Equal(t, usecase.DefaultProductCode, resp.Loans[0].ProductCode)
+	}
+
+	resp = searchLoans(t, url.Values{"customer_id": {customerID}, "product_code": {"OTHER-PRODUCT"}}, nil)
+	assert.Empty(t, resp.Loans)
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"billing/internal/auth"
+	"billing/internal/model"
+	"billing/internal/util"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func portfolioReport(t *testing.T, from, to time.Time) model.PortfolioReport {
+	query := url.Values{"from": {from.Format(time.DateOnly)}, "to": {to.Format(time.DateOnly)}}
+	rec := serve(newServer(), http.MethodGet, "/reports/portfolio?"+query.Encode(), nil, nil)
+	if !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	report, err := unmarshalResponse[model.PortfolioReport](rec)
+	assert.NoError(t, err)
+	return report
+}
+
+// TestPortfolioReport tests GET /reports/portfolio totals flows over the period and balances at its end.
+// Loans other tests booked earlier also count towards balances, so it compares
+// against the same reports taken before its own loans were booked.
+func TestPortfolioReport(t *testing.T) {
+	restore := addTimeNow(-1000)
+	start := util.GetCurrentTime()
+	restore()
+
+	sixWeeks := portfolioReport(t, start, start.AddDate(0, 0, 42))
+	weekTwo := portfolioReport(t, start.AddDate(0, 0, 14), start.AddDate(0, 0, 21))
+	weekOne := portfolioReport(t, start, start.AddDate(0, 0, 7))
+
+	restore = addTimeNow(-1000)
+	paid := createLoanFor(t, fmt.Sprintf("cust-%d", randomNumber()), 5000000)
+	createLoanFor(t, fmt.Sprintf("cust-%d", randomNumber()), 5000000)
+	restore()
+
+	restore = addTimeNow(-999)
+	rec := serve(newServer(), http.MethodPost, "/bills/"+paid.ID+"/payments", model.MakePaymentRequest{
+		PaymentAmount: 110000,
+		PaymentDate:   start.AddDate(0, 0, 7),
+	}, nil)
+	restore()
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Six weeks in, the paid loan's oldest unpaid bill is 28 days late and
+	// the other loan's is 35 days late.
+	report := portfolioReport(t, start, start.AddDate(0, 0, 42))
+	assert.Equal(t, sixWeeks.LoansDisbursed+2, report.LoansDisbursed)
+	assert.InDelta(t, sixWeeks.TotalDisbursed+10000000, report.TotalDisbursed, 0.01)
+	assert.Equal(t, sixWeeks.PaymentsCollected+1, report.PaymentsCollected)
+	assert.InDelta(t, sixWeeks.TotalCollected+110000, report.TotalCollected, 0.01)
+	assert.Equal(t, sixWeeks.ActiveLoans+2, report.ActiveLoans)
+	assert.InDelta(t, sixWeeks.TotalOutstanding+10890000, report.TotalOutstanding, 0.01)
+	assert.Equal(t, sixWeeks.DelinquentLoans+2, report.DelinquentLoans)
+	assert.InDelta(t, sixWeeks.DelinquentOutstanding+10890000, report.DelinquentOutstanding, 0.01)
+	assert.InDelta(t, sixWeeks.PAR30*sixWeeks.TotalOutstanding+5500000, report.PAR30*report.TotalOutstanding, 0.01)
+	assert.InDelta(t, sixWeeks.PAR90*sixWeeks.TotalOutstanding, report.PAR90*report.TotalOutstanding, 0.01)
+
+	// From week two on nothing was disbursed or collected, and at the end of
+	// week one only the unpaid loan's first bill was overdue.
+	report = portfolioReport(t, start.AddDate(0, 0, 14), start.AddDate(0, 0, 21))
+	assert.Equal(t, weekTwo.LoansDisbursed, report.LoansDisbursed)
+	assert.Equal(t, weekTwo.PaymentsCollected, report.PaymentsCollected)
+	assert.InDelta(t, weekTwo.TotalOutstanding+10890000, report.TotalOutstanding, 0.01)
+
+	report = portfolioReport(t, start, start.AddDate(0, 0, 7))
+	assert.Equal(t, weekOne.DelinquentLoans, report.DelinquentLoans)
+	assert.InDelta(t, weekOne.TotalOutstanding+10890000, report.TotalOutstanding, 0.01)
+}
+
+// TestPortfolioReport_InvalidPeriod tests GET /reports/portfolio rejects a malformed or reversed period
+func TestPortfolioReport_InvalidPeriod(t *testing.T) {
+	rec := serve(newServer(), http.MethodGet, "/reports/portfolio?from=2026-02-01&to=2026-01-01", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(newServer(), http.MethodGet, "/reports/portfolio?from=01-02-2026", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	if assert.Len(t, detail.Fields, 1) {
+		assert.Equal(t, "from", detail.Fields[0].Field)
+	}
+}
+
+// TestPortfolioReport_Permissions tests borrowers cannot read portfolio reports while staff can
+func TestPortfolioReport_Permissions(t *testing.T) {
+	cfg, _ := authConfig(t)
+	e := newServerWith(cfg)
+
+	rec := serve(e, http.MethodGet, "/reports/portfolio", nil, http.Header{auth.HeaderAPIKey: {testViewerKey}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	token := signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), "cust-1", []string{auth.RoleBorrower}, time.Hour)
+	rec = serve(e, http.MethodGet, "/reports/portfolio", nil, bearer(token))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
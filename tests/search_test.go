@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"billing/internal/auth"
+	"billing/internal/model"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func createLoanFor(t *testing.T, customerID string, amount float64) model.Loan {
+	loan := loanRequestFor(customerID)
+	loan.Amount = amount
+	rec := serve(newServer(), http.MethodPost, "/bills", loan, nil)
+	if !assert.Equal(t, http.StatusCreated, rec.Code) {
+		t.FailNow()
+	}
+	created, err := unmarshalResponse[model.LoanWithBills](rec)
+	assert.NoError(t, err)
+	return created.Loan
+}
+
+func searchLoans(t *testing.T, query url.Values, header http.Header) model.LoanPage {
+	rec := serve(newServer(), http.MethodGet, "/loans?"+query.Encode(), nil, header)
+	if !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	page, err := unmarshalResponse[model.LoanPage](rec)
+	assert.NoError(t, err)
+	return page
+}
+
+func loanIDs(loans []model.ProductLoan) []string {
+	ids := make([]string, 0, len(loans))
+	for _, loan := range loans {
+		ids = append(ids, loan.ID)
+	}
+	return ids
+}
+
+// TestSearchLoans_Pagination tests GET /loans walks every loan exactly once across pages, even when created_at ties
+func TestSearchLoans_Pagination(t *testing.T) {
+	customerID := fmt.Sprintf("cust-%d", randomNumber())
+	defer addTimeNow(0)()
+	var want []string
+	for range 5 {
+		want = append([]string{createLoanFor(t, customerID, 1000000).ID}, want...)
+	}
+
+	var got []string
+	query := url.Values{"customer_id": {customerID}, "limit": {"2"}}
+	for page := 0; page < 5; page++ {
+		resp := searchLoans(t, query, nil)
+		assert.LessOrEqual(t, len(resp.Loans), 2)
+		got = append(got, loanIDs(resp.Loans)...)
+		if resp.NextCursor == "" {
+			break
+		}
+		query.Set("cursor", resp.NextCursor)
+	}
+
+	assert.ElementsMatch(t, want, got)
+	assert.Len(t, got, 5)
+}
+
+// TestSearchLoans_CursorDirection tests GET /loans rejects a cursor issued for the other sort direction
+func TestSearchLoans_CursorDirection(t *testing.T) {
+	customerID := fmt.Sprintf("cust-%d", randomNumber())
+	for _, amount := range []float64{1000000, 2000000, 3000000} {
+		createLoanFor(t, customerID, amount)
+	}
+
+	query := url.Values{"customer_id": {customerID}, "sort": {"amount"}, "limit": {"1"}}
+	resp := searchLoans(t, query, nil)
+	if !assert.NotEmpty(t, resp.NextCursor) {
+		t.FailNow()
+	}
+
+	query.Set("sort", "-amount")
+	query.Set("cursor", resp.NextCursor)
+	rec := serve(newServer(), http.MethodGet, "/loans?"+query.Encode(), nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	if assert.Len(t, detail.Fields, 1) {
+		assert.Equal(t, "cursor", detail.Fields[0].Field)
+	}
+
+	query.Set("sort", "amount")
+	resp = searchLoans(t, query, nil)
+	assert.Len(t, resp.Loans, 1)
+}
+
+// TestSearchLoans_SortAndOutstanding tests GET /loans sorts by amount and filters by outstanding range
+func TestSearchLoans_SortAndOutstanding(t *testing.T) {
+	customerID := fmt.Sprintf("cust-%d", randomNumber())
+	small := createLoanFor(t, customerID, 1000000)
+	large := createLoanFor(t, customerID, 3000000)
+
+	resp := searchLoans(t, url.Values{"customer_id": {customerID}, "sort": {"-amount"}}, nil)
+	assert.Equal(t, []string{large.ID, small.ID}, loanIDs(resp.Loans))
+
+	resp = searchLoans(t, url.Values{"customer_id": {customerID}, "sort": {"amount"}}, nil)
+	assert.Equal(t, []string{small.ID, large.ID}, loanIDs(resp.Loans))
+
+	resp = searchLoans(t, url.Values{"customer_id": {customerID}, "min_outstanding": {"2000000"}}, nil)
+	assert.Equal(t, []string{large.ID}, loanIDs(resp.Loans))
+
+	resp = searchLoans(t, url.Values{"customer_id": {customerID}, "max_outstanding": {"2000000"}, "status": {model.LoanStatusInProgress}}, nil)
+	assert.Equal(t, []string{small.ID}, loanIDs(resp.Loans))
+
+	resp = searchLoans(t, url.Values{"customer_id": {customerID}, "status": {model.LoanStatusCompleted}}, nil)
+	assert.Empty(t, resp.Loans)
+}
+
+// TestSearchLoans_DelinquentAndCreatedRange tests GET /loans filters by delinquency and creation date
+func TestSearchLoans_DelinquentAndCreatedRange(t *testing.T) {
+	late := fmt.Sprintf("cust-%d", randomNumber())
+	current := fmt.Sprintf("cust-%d", randomNumber())
+
+	restore := addTimeNow(-3)
+	old := createLoanFor(t, late, 1000000)
+	restore()
+	recent := createLoanFor(t, current, 1000000)
+
+	resp := searchLoans(t, url.Values{"customer_id": {late}, "delinquent": {"true"}}, nil)
+	assert.Equal(t, []string{old.ID}, loanIDs(resp.Loans))
+
+	resp = searchLoans(t, url.Values{"customer_id": {current}, "delinquent": {"true"}}, nil)
+	assert.Empty(t, resp.Loans)
+
+	resp = searchLoans(t, url.Values{"customer_id": {current}, "delinquent": {"false"}}, nil)
+	assert.Equal(t, []string{recent.ID}, loanIDs(resp.Loans))
+
+	today := time.Now().Format(time.DateOnly)
+	resp = searchLoans(t, url.Values{"customer_id": {late}, "created_from": {today}}, nil)
+	assert.Empty(t, resp.Loans)
+
+	resp = searchLoans(t, url.Values{"customer_id": {late}, "created_to": {today}}, nil)
+	assert.Equal(t, []string{old.ID}, loanIDs(resp.Loans))
+}
+
+// TestSearchLoans_InvalidQuery tests GET /loans reports every invalid filter
+func TestSearchLoans_InvalidQuery(t *testing.T) {
+	query := url.Values{
+		"status":       {"OPEN"},
+		"sort":         {"name"},
+		"limit":        {"0"},
+		"created_from": {"2026-02-01"},
+		"created_to":   {"2026-01-01"},
+		"delinquent":   {"maybe"},
+	}
+	rec := serve(newServer(), http.MethodGet, "/loans?"+query.Encode(), nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	fields := map[string]string{}
+	for _, f := range detail.Fields {
+		fields[f.Field] = f.Code
+	}
+	assert.Equal(t, map[string]string{
+		"status":     "NOT_ALLOWED",
+		"sort":       "NOT_ALLOWED",
+		"limit":      "OUT_OF_RANGE",
+		"created_to": "OUT_OF_RANGE",
+		"delinquent": "INVALID_FORMAT",
+	}, fields)
+
+	rec = serve(newServer(), http.MethodGet, "/loans?cursor=not-a-cursor", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// TestSearchLoans_BorrowerScope tests a borrower token only finds its own customer's loans
+func TestSearchLoans_BorrowerScope(t *testing.T) {
+	own := fmt.Sprintf("cust-%d", randomNumber())
+	other := fmt.Sprintf("cust-%d", randomNumber())
+	mine := createLoanFor(t, own, 1000000)
+	createLoanFor(t, other, 1000000)
+
+	cfg, _ := authConfig(t)
+	e := newServerWith(cfg)
+	token := signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), own, []string{auth.RoleBorrower}, time.Hour)
+
+	rec := serve(e, http.MethodGet, "/loans", nil, bearer(token))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	page, err := unmarshalResponse[model.LoanPage](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{mine.ID}, loanIDs(page.Loans))
+
+	rec = serve(e, http.MethodGet, "/loans?customer_id="+other, nil, bearer(token))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	page, err = unmarshalResponse[model.LoanPage](rec)
+	assert.NoError(t, err)
+	assert.Empty(t, page.Loans)
+}
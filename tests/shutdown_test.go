@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"billing/api"
+	"billing/internal/repository"
+	"billing/pkg/db"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestServer_GracefulShutdown tests SIGTERM during a slow request lets the request finish before the server stops
+func TestServer_GracefulShutdown(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	assert.NoError(t, listener.Close())
+
+	cfg := testConfig()
+	cfg.Server.Port = port
+	cfg.DB.DSN = filepath.Join(t.TempDir(), "shutdown.db")
+
+	conn, err := db.Open(cfg.DB)
+	assert.NoError(t, err)
+	_, err = db.MigrateUp(conn)
+	assert.NoError(t, err)
+
+	started := make(chan struct{})
+	e := api.New(cfg, repository.NewMemoryRepositories())
+	e.HideBanner = true
+	e.GET("/slow", func(c echo.Context) error {
+		close(started)
+		time.Sleep(500 * time.Millisecond)
+		return c.String(http.StatusOK, "done")
+	})
+
+	exited := make(chan int, 1)
+	server := api.Server{Echo: e, Config: cfg, DB: conn, Exit: func(code int) { exited <- code }}
+	server.Attach()
+	// StartServer keeps waiting on its own listener after the shutdown; the
+	// process would have exited by then.
+	go api.StartServer(e)
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/slow", port)
+	var resp *http.Response
+	responded := make(chan error, 1)
+	go func() {
+		for i := 0; i < 50; i++ {
+			resp, err = http.Get(url)
+			if err == nil {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		responded <- err
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow request never reached the server")
+	}
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	assert.NoError(t, <-responded)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "done", string(body))
+
+	select {
+	case code := <-exited:
+		assert.Equal(t, 0, code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after SIGTERM")
+	}
+
+	_, err = http.Get(url)
+	assert.Error(t, err, "server should not accept connections after shutdown")
+
+	sqlDB, err := conn.DB()
+	assert.NoError(t, err)
+	assert.Error(t, sqlDB.Ping(), "database should be closed after shutdown")
+}
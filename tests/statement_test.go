@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"billing/internal/auth"
+	"billing/internal/export"
+	"billing/internal/model"
+	"billing/internal/usecase"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+const goldenLoanID = "loan-statement-golden"
+
+// seedGoldenLoan stores a loan with fixed IDs and dates, disbursed on
+// 5 January 2026 with its first three bills paid on their due dates. Times
+// are at noon UTC so every time zone sees the same calendar days.
+func seedGoldenLoan(t *testing.T) {
+	// Starting a server seeds the default product the loan refers to.
+	newServer()
+	ctx := context.Background()
+	if _, err := backend.repos.Loans.GetByID(ctx, goldenLoanID); err == nil {
+		return
+	}
+
+	created := time.Date(2026, time.January, 5, 12, 0, 0, 0, time.UTC)
+	loan := model.Loan{
+		ID:           goldenLoanID,
+		CustomerID:   "cust-statement-golden",
+		Name:         "Golden Loan",
+		Period:       50,
+		Amount:       5000000,
+		InterestRate: 10,
+		TotalAmount:  5500000,
+		Outstanding:  5500000 - 3*110000,
+		Status:       model.LoanStatusInProgress,
+		CreatedAt:    created,
+	}
+	bills := make([]model.Billing, 0, loan.Period)
+	for i := range loan.Period {
+		due := created.AddDate(0, 0, 7*(i+1))
+		b := model.Billing{
+			ID:        fmt.Sprintf("%s-%02d", goldenLoanID, i+1),
+			LoanID:    loan.ID,
+			Sequence:  i + 1,
+			Date:      created,
+			DueDate:   due,
+			Amount:    110000,
+			CreatedAt: created,
+		}
+		if i < 3 {
+			b.PaymentDate = &due
+		}
+		bills = append(bills, b)
+	}
+
+	if err := backend.repos.Loans.Create(ctx, &model.ProductLoan{Loan: loan, ProductCode: usecase.DefaultProductCode}); err != nil {
+		t.Fatalf("Failed to seed loan: %v", err)
+	}
+	if err := backend.repos.Billings.CreateBatch(ctx, bills); err != nil {
+		t.Fatalf("Failed to seed bills: %v", err)
+	}
+}
+
+// TestLoanStatement_Golden tests GET /loans/:loan_id/statement.pdf renders the same PDF as testdata/statement.golden.pdf.
+// Run with -update to rewrite the golden file after an intended layout change.
+func TestLoanStatement_Golden(t *testing.T) {
+	seedGoldenLoan(t)
+
+	rec := serve(newServer(), http.MethodGet, "/loans/"+goldenLoanID+"/statement.pdf?as_of=2026-03-02", nil, nil)
+	if !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		return
+	}
+	assert.Equal(t, export.MIMEPDF, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "loan-"+goldenLoanID+"-statement-2026-03-02.pdf")
+
+	golden := filepath.Join("testdata", "statement.golden.pdf")
+	if *updateGolden {
+		if err := os.WriteFile(golden, rec.Body.Bytes(), 0o644); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Missing %s, run the test with -update to create it", golden)
+	}
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(want, rec.Body.Bytes()), "statement differs from %s; run with -update if the change is intended", golden)
+}
+
+// TestLoanStatement_AsOf tests the statement summary follows the as_of date
+func TestLoanStatement_AsOf(t *testing.T) {
+	seedGoldenLoan(t)
+
+	// On 2 March eight bills had fallen due and three were paid, so the loan
+	// was delinquent from its fifth due date; on 19 January it was current.
+	st, err := newStatement(t, "2026-03-02")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, st.PaymentsReceived)
+		assert.InDelta(t, 330000, st.TotalPaid, 0.01)
+		assert.InDelta(t, 5170000, st.Outstanding, 0.01)
+		assert.True(t, st.IsDelinquent)
+		if assert.NotNil(t, st.DelinquentAt) {
+			assert.Equal(t, "2026-02-09", st.DelinquentAt.UTC().Format(time.DateOnly))
+		}
+	}
+
+	st, err = newStatement(t, "2026-01-19")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, st.PaymentsReceived)
+		assert.False(t, st.IsDelinquent)
+	}
+}
+
+func newStatement(t *testing.T, asOf string) (*model.LoanStatement, error) {
+	uc := usecase.LoanUsecase{Loans: backend.repos.Loans, Billings: backend.repos.Billings}
+	date, err := time.ParseInLocation(time.DateOnly, asOf, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uc.Statement(context.Background(), goldenLoanID, date)
+}
+
+// TestLoanStatement_Errors tests GET /loans/:loan_id/statement.pdf rejects future dates, unknown loans and other borrowers
+func TestLoanStatement_Errors(t *testing.T) {
+	seedGoldenLoan(t)
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+	rec := serve(newServer(), http.MethodGet, "/loans/"+goldenLoanID+"/statement.pdf?as_of="+tomorrow, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(newServer(), http.MethodGet, "/loans/missing-loan/statement.pdf", nil, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	cfg, _ := authConfig(t)
+	token := signToken(t, jwt.SigningMethodHS256, []byte(testHS256Secret), "cust-someone-else", []string{auth.RoleBorrower}, time.Hour)
+	rec = serve(newServerWith(cfg), http.MethodGet, "/loans/"+goldenLoanID+"/statement.pdf", nil, bearer(token))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"billing/internal/model"
+	"billing/internal/tracing"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans routes every span to an in-memory exporter for the rest of the test.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func spanNamed(spans tracetest.SpanStubs, name string) (tracetest.SpanStub, bool) {
+	for _, span := range spans {
+		if span.Name == name {
+			return span, true
+		}
+	}
+	return tracetest.SpanStub{}, false
+}
+
+func loanIDOf(span tracetest.SpanStub) string {
+	for _, attr := range span.Attributes {
+		if attr.Key == tracing.KeyLoanID {
+			return attr.Value.AsString()
+		}
+	}
+	return ""
+}
+
+// TestTracing_MakePayment tests a payment produces nested handler, usecase and database spans tagged with the loan ID
+func TestTracing_MakePayment(t *testing.T) {
+	loan, err := seedData()
+	if err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	exporter := recordSpans(t)
+
+	req := mapAPI[APIMakePayment]
+	req.Param = map[string]string{
+		"loan_id": loan.Loan.ID,
+	}
+	req.Body = model.MakePaymentRequest{
+		PaymentAmount: loan.Bills[0].Amount,
+		PaymentDate:   loan.Bills[0].DueDate,
+	}
+	rec := callAPI(req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	spans := exporter.GetSpans()
+	handlerSpan, ok := spanNamed(spans, "POST /bills/:loan_id/payments")
+	if !assert.True(t, ok, "handler span") {
+		return
+	}
+	usecaseSpan, ok := spanNamed(spans, "LoanUsecase.MakePayment")
+	if !assert.True(t, ok, "usecase span") {
+		return
+	}
+	assert.Equal(t, loan.Loan.ID, loanIDOf(handlerSpan))
+	assert.Equal(t, loan.Loan.ID, loanIDOf(usecaseSpan))
+	assert.Equal(t, handlerSpan.SpanContext.SpanID(), usecaseSpan.Parent.SpanID())
+
+	if backend.db == nil {
+		return
+	}
+	querySpan, ok := spanNamed(spans, "gorm.query")
+	if assert.True(t, ok, "database span") {
+		assert.Equal(t, usecaseSpan.SpanContext.TraceID(), querySpan.SpanContext.TraceID())
+	}
+}
+
+// TestTracing_ProbesNotTraced tests health probes do not create spans
+func TestTracing_ProbesNotTraced(t *testing.T) {
+	exporter := recordSpans(t)
+
+	rec := callAPI(APIRequest{Method: http.MethodGet, Path: "/healthz"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	_, ok := spanNamed(exporter.GetSpans(), "GET /healthz")
+	assert.False(t, ok)
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"billing/internal/model"
+	"billing/internal/util"
+	"billing/internal/validation"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type errorDetail struct {
+	Code   string            `json:"code"`
+	Fields validation.Errors `json:"fields"`
+}
+
+// TestCreateBills_FieldErrors tests POST /bills reports every invalid field with its code
+func TestCreateBills_FieldErrors(t *testing.T) {
+	reqBody := model.Loan{
+		Period:       0,
+		Amount:       -1,
+		InterestRate: 10,
+	}
+	req := mapAPI[APICreatedBill]
+	req.Body = reqBody
+	rec := callAPI(req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, "VALIDATION_FAILED", detail.Code)
+	codes := map[string]string{}
+	for _, e := range detail.Fields {
+		codes[e.Field] = e.Code
+	}
+	assert.Equal(t, validation.CodeRequired, codes["customer_id"])
+	assert.Equal(t, validation.CodeMustBePositive, codes["amount"])
+	assert.Equal(t, validation.CodeOutOfRange, codes["period"])
+}
+
+// TestMakePayment_FutureDate tests POST /bills/:loan_id/payments rejects a payment_date more than the allowed lead ahead
+func TestMakePayment_FutureDate(t *testing.T) {
+	loan, err := seedData()
+	if err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	reqBody := model.MakePaymentRequest{
+		PaymentAmount: 110000,
+		PaymentDate:   util.GetCurrentTime().AddDate(0, 1, 0),
+	}
+	req := mapAPI[APIMakePayment]
+	req.Param = map[string]string{
+		"loan_id": loan.Loan.ID,
+	}
+	req.Body = reqBody
+	rec := callAPI(req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, validation.Errors{{
+		Field:   "payment_date",
+		Code:    validation.CodeTooFarAhead,
+		Message: "payment_date must not be more than 7 days ahead",
+	}}, detail.Fields)
+}
+
+// TestGetBills_NotFound tests GET /bills/:loan_id returns 404 with a stable code for an unknown loan
+func TestGetBills_NotFound(t *testing.T) {
+	req := mapAPI[APIGetBill]
+	req.Param = map[string]string{
+		"loan_id": "missing-loan",
+	}
+	rec := callAPI(req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	detail, err := unmarshalResponse[errorDetail](rec)
+	assert.NoError(t, err)
+	assert.Equal(t, "LOAN_NOT_FOUND", detail.Code)
+}